@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"node-module-man/internal/deleter"
+	"node-module-man/internal/journal"
+	"node-module-man/internal/scanner"
+	"node-module-man/pkg/utils"
+)
+
+// pruneResult is the JSON form of one cache cleanup.
+type pruneResult struct {
+	Kind    string `json:"kind"` // a cache kind, "tools" or "runtimes"
+	Path    string `json:"path,omitempty"`
+	Action  string `json:"action"`
+	Entries int    `json:"entries"`
+	Kept    int    `json:"kept"`
+	Freed   int64  `json:"freed"`
+	Failed  int    `json:"failed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runCaches implements `node-module-man caches`: list the package manager
+// caches, stores, tool downloads and Node runtimes, and optionally prune what
+// no scanned project needs.
+func runCaches(args []string) int {
+	fs := flag.NewFlagSet("caches", flag.ExitOnError)
+	var (
+		root        string
+		jsonOut     bool
+		concurrency int
+		maxDepth    int
+		excludes    multiFlag
+		prune       multiFlag
+		pruneTools  bool
+		pruneNode   bool
+		yes         bool
+		dryRun      bool
+	)
+	fs.StringVar(&root, "path", ".", "Root path to scan for projects")
+	fs.StringVar(&root, "p", ".", "Alias of --path")
+	fs.BoolVar(&jsonOut, "json", false, "Output JSON instead of table")
+	fs.IntVar(&concurrency, "concurrency", runtime.NumCPU(), "Concurrency for directory discovery and size calculations")
+	fs.IntVar(&concurrency, "c", runtime.NumCPU(), "Alias of --concurrency")
+	fs.IntVar(&maxDepth, "max-depth", -1, "Max depth for directory walk (-1 for unlimited)")
+	fs.IntVar(&maxDepth, "m", -1, "Alias of --max-depth")
+	fs.Var(&excludes, "exclude", "Gitignore-style pattern to exclude, relative to --path (can repeat)")
+	fs.Var(&excludes, "x", "Alias of --exclude")
+	fs.Var(&prune, "prune", "Prune caches of this kind, a cache path, or all (can repeat)")
+	fs.BoolVar(&pruneTools, "prune-tools", false, "Delete cached Playwright, Cypress, Puppeteer and Electron versions no scanned project needs")
+	fs.BoolVar(&pruneNode, "prune-runtimes", false, "Delete Node versions installed by nvm, fnm or volta that no scanned project requests")
+	fs.BoolVar(&yes, "yes", false, "Do not prompt for confirmation when pruning")
+	fs.BoolVar(&dryRun, "dry-run", false, "Report what pruning would remove without deleting")
+	fs.BoolVar(&dryRun, "d", false, "Alias of --dry-run")
+	_ = fs.Parse(args)
+
+	if (len(prune) > 0 || pruneTools || pruneNode) && !yes && !dryRun {
+		fmt.Fprintln(os.Stderr, "--yes (or --dry-run) is required to prune caches. Aborting.")
+		return 2
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to resolve path: %v\n", err)
+		return 2
+	}
+	start := time.Now()
+	ctx := context.Background()
+	opts := scanner.Options{
+		Concurrency: concurrency,
+		MaxDepth:    maxDepth,
+		Excludes:    []string(excludes),
+		IgnoreFiles: true,
+	}
+	rep, scanErrs := scanner.ScanCaches(ctx, absRoot, opts)
+	printScanErrors(scanErrs)
+
+	var pruned []pruneResult
+	failed := false
+	for _, c := range rep.Caches {
+		if !pruneSelected(c, prune) {
+			continue
+		}
+		res := pruneResult{Kind: string(c.Kind), Path: c.Path}
+		plan, err := scanner.PlanPrune(ctx, c, rep.Projects)
+		res.Action, res.Entries, res.Kept = plan.Action, len(plan.Entries), plan.Kept
+		if err != nil {
+			res.Error = err.Error()
+			// refusing to prune for lack of references is not a failure
+			failed = failed || !errors.Is(err, scanner.ErrNoReferences)
+			pruned = append(pruned, res)
+			continue
+		}
+		sum := deleteEntries(ctx, plan.Entries, concurrency, dryRun)
+		res.Freed, res.Failed = sum.Freed, len(sum.Failures)
+		failed = failed || len(sum.Failures) > 0
+		pruned = append(pruned, res)
+	}
+	if pruneTools {
+		plan, err := scanner.PlanToolPrune(rep.Tools, rep.Projects)
+		res := pruneResult{Kind: "tools", Action: plan.Action, Entries: len(plan.Entries), Kept: plan.Kept}
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			sum := deleteEntries(ctx, plan.Entries, concurrency, dryRun)
+			res.Freed, res.Failed = sum.Freed, len(sum.Failures)
+			failed = failed || len(sum.Failures) > 0
+		}
+		pruned = append(pruned, res)
+	}
+	if pruneNode {
+		plan, err := scanner.PlanRuntimePrune(rep.Runtimes, rep.Projects)
+		res := pruneResult{Kind: "runtimes", Action: plan.Action, Entries: len(plan.Entries), Kept: plan.Kept}
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			sum := deleteEntries(ctx, plan.Entries, concurrency, dryRun)
+			res.Freed, res.Failed = sum.Freed, len(sum.Failures)
+			failed = failed || len(sum.Failures) > 0
+		}
+		pruned = append(pruned, res)
+	}
+
+	var totalSize, totalReclaimable int64
+	for _, c := range rep.Caches {
+		totalSize += c.Size
+		totalReclaimable += c.Reclaimable
+	}
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		payload := struct {
+			Root             string                `json:"root"`
+			Projects         int                   `json:"projects"`
+			TotalSize        int64                 `json:"totalSize"`
+			TotalReclaimable int64                 `json:"totalReclaimable"`
+			Caches           []scanner.Cache       `json:"caches"`
+			Tools            []scanner.ToolVersion `json:"tools"`
+			Runtimes         []scanner.NodeRuntime `json:"runtimes"`
+			Pruned           []pruneResult         `json:"pruned,omitempty"`
+			DryRun           bool                  `json:"dryRun,omitempty"`
+			Errors           []*scanner.ScanError  `json:"errors"`
+			Duration         string                `json:"duration"`
+		}{Root: absRoot, Projects: len(rep.Projects), TotalSize: totalSize, TotalReclaimable: totalReclaimable, Caches: rep.Caches, Tools: rep.Tools, Runtimes: rep.Runtimes, Pruned: pruned, DryRun: dryRun, Errors: scanErrs, Duration: time.Since(start).String()}
+		if payload.Caches == nil {
+			payload.Caches = []scanner.Cache{}
+		}
+		if payload.Tools == nil {
+			payload.Tools = []scanner.ToolVersion{}
+		}
+		if payload.Runtimes == nil {
+			payload.Runtimes = []scanner.NodeRuntime{}
+		}
+		if payload.Errors == nil {
+			payload.Errors = []*scanner.ScanError{}
+		}
+		if err := enc.Encode(payload); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write json: %v\n", err)
+			return 1
+		}
+	} else {
+		fmt.Printf("node-module-man caches\nroot: %s\nprojects: %d  caches: %d\n", absRoot, len(rep.Projects), len(rep.Caches))
+		fmt.Println("kind\treclaimable\tapparent\tfiles\tpath")
+		fmt.Println("----------------------------------------------")
+		for _, c := range rep.Caches {
+			sizeStr := utils.HumanizeBytes(c.Reclaimable) + "\t" + utils.HumanizeBytes(c.Size)
+			if c.Partial {
+				sizeStr = "≥ " + utils.HumanizeBytes(c.Reclaimable) + "\t≥ " + utils.HumanizeBytes(c.Size)
+			}
+			line := fmt.Sprintf("%s\t%s\t%s\t%s", c.Kind, sizeStr, utils.HumanizeCount(c.Files), c.Path)
+			if c.Err != nil {
+				line += fmt.Sprintf("\t(ERROR: %v)", c.Err)
+			}
+			fmt.Println(line)
+		}
+		fmt.Println("----------------------------------------------")
+		fmt.Printf("Total size: %s apparent, %s reclaimable\n", utils.HumanizeBytes(totalSize), utils.HumanizeBytes(totalReclaimable))
+		if len(rep.Tools) > 0 {
+			var unused int64
+			fmt.Println("\ntool\tversion\treclaimable\tstatus\tpath")
+			fmt.Println("----------------------------------------------")
+			for _, v := range rep.Tools {
+				fmt.Printf("%s\t%s\t%s\t%s\t%s\n", v.Tool, v.Name+"@"+v.Version, utils.HumanizeBytes(v.Reclaimable), v.Status, v.Path)
+				if v.Status == scanner.ToolUnused {
+					unused += v.Reclaimable
+				}
+			}
+			fmt.Println("----------------------------------------------")
+			fmt.Printf("Unused tool versions: %s reclaimable (--prune-tools)\n", utils.HumanizeBytes(unused))
+		}
+		if len(rep.Runtimes) > 0 {
+			var unused int64
+			fmt.Println("\nmanager\tnode\treclaimable\tstatus\tglobals\tpath")
+			fmt.Println("----------------------------------------------")
+			for _, rt := range rep.Runtimes {
+				status := string(rt.Status)
+				if rt.Default {
+					status += " (default)"
+				} else if rt.Status == scanner.ToolUnused {
+					unused += rt.Reclaimable
+				}
+				globals := "-"
+				if len(rt.Globals) > 0 {
+					globals = strings.Join(rt.Globals, ",")
+				}
+				fmt.Printf("%s\t%s\t%s\t%s\t%s\t%s\n", rt.Manager, rt.Version, utils.HumanizeBytes(rt.Reclaimable), status, globals, rt.Path)
+			}
+			fmt.Println("----------------------------------------------")
+			fmt.Printf("Unused Node versions: %s reclaimable (--prune-runtimes)\n", utils.HumanizeBytes(unused))
+		}
+		for _, p := range pruned {
+			verb := "Pruned"
+			if dryRun {
+				verb = "Would prune"
+			}
+			label := p.Kind
+			if p.Path != "" {
+				label += " " + p.Path
+			}
+			if p.Error != "" {
+				fmt.Printf("%s: skipped: %s\n", label, p.Error)
+				continue
+			}
+			fmt.Printf("%s (%s): %s %d entries, %s; kept %d", label, p.Action, verb, p.Entries, utils.HumanizeBytes(p.Freed), p.Kept)
+			if p.Failed > 0 {
+				fmt.Printf("; %d failed", p.Failed)
+			}
+			fmt.Println()
+		}
+		fmt.Printf("Duration: %s\n", time.Since(start).Round(time.Millisecond))
+	}
+	if len(scanErrs) > 0 || failed {
+		return 1
+	}
+	return 0
+}
+
+// deleteEntries removes prune entries through the deleter and journals
+// what it removed.
+func deleteEntries(ctx context.Context, entries []scanner.PruneEntry, concurrency int, dryRun bool) deleter.Summary {
+	targets := make([]deleter.Target, 0, len(entries))
+	for _, e := range entries {
+		targets = append(targets, deleter.Target{Path: e.Path, Size: e.Size, Files: e.Files})
+	}
+	sum := deleter.DeleteTargets(ctx, targets, deleter.Options{Concurrency: concurrency, DryRun: dryRun}, nil)
+	if !dryRun {
+		recordJournal(journal.FromDelete(sum, journal.OpDelete))
+	}
+	return sum
+}
+
+// pruneSelected reports whether a --prune value names c: its kind, its
+// path or "all".
+func pruneSelected(c scanner.Cache, prune []string) bool {
+	for _, p := range prune {
+		if p == "all" || p == string(c.Kind) {
+			return true
+		}
+		if abs, err := filepath.Abs(p); err == nil && abs == c.Path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"node-module-man/internal/scanner"
+	"node-module-man/pkg/utils"
+)
+
+// runDupes implements `node-module-man dupes`: scan for node_modules, then
+// report name@version packages installed more than once across them.
+func runDupes(args []string) int {
+	fs := flag.NewFlagSet("dupes", flag.ExitOnError)
+	var (
+		root        string
+		jsonOut     bool
+		concurrency int
+		maxDepth    int
+		excludes    multiFlag
+		top         int
+	)
+	fs.StringVar(&root, "path", ".", "Root path to scan")
+	fs.StringVar(&root, "p", ".", "Alias of --path")
+	fs.BoolVar(&jsonOut, "json", false, "Output JSON instead of table")
+	fs.IntVar(&concurrency, "concurrency", runtime.NumCPU(), "Concurrency for directory discovery and size calculations")
+	fs.IntVar(&concurrency, "c", runtime.NumCPU(), "Alias of --concurrency")
+	fs.IntVar(&maxDepth, "max-depth", -1, "Max depth for directory walk (-1 for unlimited)")
+	fs.IntVar(&maxDepth, "m", -1, "Alias of --max-depth")
+	fs.Var(&excludes, "exclude", "Gitignore-style pattern to exclude, relative to --path (can repeat)")
+	fs.Var(&excludes, "x", "Alias of --exclude")
+	fs.IntVar(&top, "top", 50, "Rows to print in table mode (0 for all)")
+	_ = fs.Parse(args)
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to resolve path: %v\n", err)
+		return 2
+	}
+	start := time.Now()
+	ctx := context.Background()
+	opts := scanner.Options{
+		Concurrency: concurrency,
+		MaxDepth:    maxDepth,
+		Excludes:    []string(excludes),
+		IgnoreFiles: true,
+	}
+	// only the directories are needed: FindDuplicates measures what it reports
+	dirs, scanErrs := scanner.FindNodeModules(ctx, absRoot, opts)
+	printScanErrors(scanErrs)
+	rep, dupErrs := scanner.FindDuplicates(ctx, dirs, opts)
+	printScanErrors(dupErrs)
+	errs := append(scanErrs, dupErrs...)
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		payload := struct {
+			Root         string               `json:"root"`
+			NodeModules  int                  `json:"nodeModules"`
+			Packages     int                  `json:"packages"`
+			Wasted       int64                `json:"wasted"`
+			StoreSavings int64                `json:"storeSavings"`
+			Duplicates   []scanner.Duplicate  `json:"duplicates"`
+			Errors       []*scanner.ScanError `json:"errors"`
+			Duration     string               `json:"duration"`
+		}{Root: absRoot, NodeModules: len(dirs), Packages: rep.Packages, Wasted: rep.Wasted, StoreSavings: rep.StoreSavings, Duplicates: rep.Duplicates, Errors: errs, Duration: time.Since(start).String()}
+		if payload.Errors == nil {
+			payload.Errors = []*scanner.ScanError{}
+		}
+		if err := enc.Encode(payload); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write json: %v\n", err)
+			return 1
+		}
+	} else {
+		fmt.Printf("node-module-man dupes\nroot: %s\nnode_modules: %d  packages: %d  duplicated: %d\n", absRoot, len(dirs), rep.Packages, len(rep.Duplicates))
+		fmt.Println("copies\tper-copy\twasted\tpackage")
+		fmt.Println("----------------------------------------------")
+		for i, d := range rep.Duplicates {
+			if top > 0 && i == top {
+				fmt.Printf("... %d more (use --top 0 or --json)\n", len(rep.Duplicates)-top)
+				break
+			}
+			fmt.Printf("%d\t%s\t%s\t%s@%s\n", d.Count, utils.HumanizeBytes(d.Size), utils.HumanizeBytes(d.Wasted), d.Name, d.Version)
+		}
+		fmt.Println("----------------------------------------------")
+		fmt.Printf("Wasted: %s apparent; a shared store would save ~%s\n", utils.HumanizeBytes(rep.Wasted), utils.HumanizeBytes(rep.StoreSavings))
+		fmt.Printf("Duration: %s\n", time.Since(start).Round(time.Millisecond))
+	}
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"node-module-man/internal/scanner"
+	"node-module-man/pkg/utils"
+)
+
+// runInspect implements `node-module-man inspect [--json] <node_modules>`:
+// the per-package breakdown of a single node_modules directory.
+func runInspect(args []string) int {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	var (
+		jsonOut     bool
+		concurrency int
+		followLinks bool
+	)
+	fs.BoolVar(&jsonOut, "json", false, "Output JSON instead of table")
+	fs.IntVar(&concurrency, "concurrency", runtime.NumCPU(), "Concurrency for size calculations")
+	fs.IntVar(&concurrency, "c", runtime.NumCPU(), "Alias of --concurrency")
+	fs.BoolVar(&followLinks, "follow-symlinks", false, "Size symlinked packages through their targets (pnpm-style)")
+	fs.BoolVar(&followLinks, "L", false, "Alias of --follow-symlinks")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: node-module-man inspect [flags] <node_modules path>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	dir, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to resolve path: %v\n", err)
+		return 2
+	}
+
+	pkgs, errs := scanner.Inspect(context.Background(), dir, scanner.Options{Concurrency: concurrency, FollowSymlink: followLinks})
+	printScanErrors(errs)
+	if pkgs == nil && len(errs) > 0 {
+		return 1
+	}
+	var total, reclaimable int64
+	for _, p := range pkgs {
+		total += p.Size
+		reclaimable += p.Reclaimable
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		payload := struct {
+			Path             string               `json:"path"`
+			TotalSize        int64                `json:"totalSize"`
+			TotalReclaimable int64                `json:"totalReclaimable"`
+			Packages         []scanner.Package    `json:"packages"`
+			Errors           []*scanner.ScanError `json:"errors"`
+		}{Path: dir, TotalSize: total, TotalReclaimable: reclaimable, Packages: pkgs, Errors: errs}
+		if payload.Errors == nil {
+			payload.Errors = []*scanner.ScanError{}
+		}
+		if err := enc.Encode(payload); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write json: %v\n", err)
+			return 1
+		}
+	} else {
+		fmt.Printf("node-module-man inspect\npath: %s\npackages: %d\n", dir, len(pkgs))
+		fmt.Println("reclaimable\tapparent\tversion\tname")
+		fmt.Println("----------------------------------------------")
+		for _, p := range pkgs {
+			version := p.Version
+			if version == "" {
+				version = "-"
+			}
+			name := p.Name
+			if p.Nested {
+				name += " (+node_modules)"
+			}
+			if p.Symlink {
+				name += " (symlink)"
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\n", utils.HumanizeBytes(p.Reclaimable), utils.HumanizeBytes(p.Size), version, name)
+		}
+		fmt.Println("----------------------------------------------")
+		fmt.Printf("Total size: %s apparent, %s reclaimable\n", utils.HumanizeBytes(total), utils.HumanizeBytes(reclaimable))
+	}
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -3,16 +3,19 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"node-module-man/internal/deleter"
 	"node-module-man/internal/compressor"
+	"node-module-man/internal/journal"
 	"node-module-man/internal/scanner"
 	ui "node-module-man/internal/tui"
 	"node-module-man/pkg/utils"
@@ -26,7 +29,23 @@ type multiFlag []string
 func (m *multiFlag) String() string     { return fmt.Sprint([]string(*m)) }
 func (m *multiFlag) Set(v string) error { *m = append(*m, v); return nil }
 
+// subcommands are dispatched on the first argument; everything else is the
+// flag-driven scan/delete/compress mode.
+var subcommands = map[string]func(args []string) int{
+	"inspect":    runInspect,
+	"dupes":      runDupes,
+	"caches":     runCaches,
+	"quarantine": runQuarantine,
+	"restore":    runRestore,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
 	var (
 		root        string
 		jsonOut     bool
@@ -36,14 +55,30 @@ func main() {
 		deleteStdin bool
 		compressJSON string
 		compressStdin bool
+		restoreJSON string
+		restoreStdin bool
+		noOverwrite bool
 		outDir      string
 		deleteAfter bool
 		concurrency int
 		maxDepth    int
 		useTUI      bool
 		dryRun      bool
+		force       bool
+		trash       bool
+		quarantine  bool
+		requireRestorable bool
 		excludes    multiFlag
 		followLinks bool
+		olderThan   string
+		newerThan   string
+		targetFlags multiFlag
+		noCache     bool
+		refresh     bool
+		noIgnore    bool
+		maxEntries  int64
+		maxBytes    string
+		dirTimeout  time.Duration
 	)
 
 	flag.StringVar(&root, "path", ".", "Root path to scan")
@@ -55,9 +90,12 @@ func main() {
 	flag.BoolVar(&deleteStdin, "delete-stdin", false, "Read delete targets JSON from stdin")
 	flag.StringVar(&compressJSON, "compress-json", "", "Compress targets from JSON file (array of paths or {path,size} objects)")
 	flag.BoolVar(&compressStdin, "compress-stdin", false, "Read compress targets JSON from stdin")
+	flag.StringVar(&restoreJSON, "restore-json", "", "Restore archives written by compress from JSON file (array of paths or {path,size} objects)")
+	flag.BoolVar(&restoreStdin, "restore-stdin", false, "Read restore archives JSON from stdin")
+	flag.BoolVar(&noOverwrite, "no-overwrite", false, "With --restore-json/--restore-stdin, refuse to restore over an existing directory instead of replacing it")
     flag.StringVar(&outDir, "out-dir", "", "Output directory for compressed archives (default: alongside source)")
     flag.BoolVar(&deleteAfter, "delete-after", true, "Delete original directory after successful compression (default true)")
-	flag.IntVar(&concurrency, "concurrency", runtime.NumCPU(), "Concurrency for size calculations")
+	flag.IntVar(&concurrency, "concurrency", runtime.NumCPU(), "Concurrency for directory discovery and size calculations")
 	flag.IntVar(&concurrency, "c", runtime.NumCPU(), "Alias of --concurrency")
 	flag.IntVar(&maxDepth, "max-depth", -1, "Max depth for directory walk (-1 for unlimited)")
 	flag.IntVar(&maxDepth, "m", -1, "Alias of --max-depth")
@@ -65,10 +103,23 @@ func main() {
 	flag.BoolVar(&useTUI, "t", true, "Alias of --tui")
 	flag.BoolVar(&dryRun, "dry-run", false, "Do not delete anything; simulate deletion in TUI")
 	flag.BoolVar(&dryRun, "d", false, "Alias of --dry-run")
-	flag.Var(&excludes, "exclude", "Glob pattern to exclude (can repeat). Matches full path or basename.")
+	flag.BoolVar(&force, "force", false, "Also delete protected targets (node_modules tracked in git)")
+	flag.BoolVar(&trash, "trash", false, "Move deleted targets to the trash (freedesktop.org spec) instead of removing them; also the TUI default")
+	flag.BoolVar(&quarantine, "quarantine", false, "Rename deleted targets into quarantine, restorable until `quarantine purge`; also the TUI default")
+	flag.BoolVar(&requireRestorable, "require-restorable", false, "With --delete-json/--delete-stdin, refuse node_modules that cannot be reinstalled fully from local caches")
+	flag.Var(&excludes, "exclude", "Gitignore-style pattern to exclude, relative to --path (can repeat). Supports **, ! and trailing /.")
 	flag.Var(&excludes, "x", "Alias of --exclude")
+	flag.BoolVar(&noIgnore, "no-nmmignore", false, "Ignore .nmmignore files found during the scan")
 	flag.BoolVar(&followLinks, "follow-symlinks", false, "Follow symlinked directories when computing sizes (pnpm-style)")
 	flag.BoolVar(&followLinks, "L", false, "Alias of --follow-symlinks")
+	flag.StringVar(&olderThan, "older-than", "", "Only list projects with no activity for at least this long (e.g. 90d, 2w, 36h)")
+	flag.StringVar(&newerThan, "newer-than", "", "Only list projects active within this window (e.g. 7d)")
+	flag.Var(&targetFlags, "target", "Directory to collect (can repeat): a preset ("+strings.Join(scanner.PresetNames(), ", ")+") or name[:sibling-glob,...]. Default: node_modules")
+	flag.BoolVar(&noCache, "no-cache", false, "Do not read or write the persistent size index")
+	flag.BoolVar(&refresh, "refresh", false, "Re-measure every directory and rewrite the size index")
+	flag.Int64Var(&maxEntries, "max-entries", 0, "Stop measuring a directory after this many entries; its size is reported as a lower bound (0 = unlimited)")
+	flag.StringVar(&maxBytes, "max-bytes", "", "Stop measuring a directory once this much is counted, e.g. 5G; reported as a lower bound")
+	flag.DurationVar(&dirTimeout, "dir-timeout", 0, "Stop measuring a directory after this long, e.g. 30s; reported as a lower bound (0 = unlimited)")
 	flag.Parse()
 
 	if showVersion {
@@ -76,6 +127,19 @@ func main() {
 		return
 	}
 
+	if trash && quarantine {
+		fmt.Fprintln(os.Stderr, "--trash and --quarantine cannot be combined.")
+		os.Exit(2)
+	}
+	removal := "delete"
+	var strategy deleter.Strategy
+	switch {
+	case trash:
+		removal, strategy = "trash", deleter.Trash{}
+	case quarantine:
+		removal, strategy = "quarantine", deleter.Quarantine{}
+	}
+
 	// Deletion CLI mode via JSON input
 	if deleteJSON != "" || deleteStdin {
 		if !yesDelete {
@@ -101,7 +165,21 @@ func main() {
 		}
 		// Execute deletions
 		ctx := context.Background()
-		sum := deleter.DeleteTargets(ctx, targets, concurrency, nil, dryRun)
+		git := scanner.NewGitTracking()
+		for i := range targets {
+			if !targets[i].Protected {
+				targets[i].Protected = git.Tracked(targets[i].Path)
+			}
+		}
+		var refused []deleter.Failure
+		if requireRestorable {
+			targets, refused = restorableTargets(targets)
+		}
+		sum := deleter.DeleteTargets(ctx, targets, deleter.Options{Concurrency: concurrency, DryRun: dryRun, Force: force, Strategy: strategy}, nil)
+		sum.Failures = append(refused, sum.Failures...)
+		if !dryRun {
+			recordJournal(journal.FromDelete(sum, journal.Op(removal)))
+		}
 		if jsonOut {
 			enc := json.NewEncoder(os.Stdout)
 			enc.SetIndent("", "  ")
@@ -110,7 +188,14 @@ func main() {
 				os.Exit(1)
 			}
 		} else {
-			fmt.Printf("Deleted: %d  Failed: %d  Freed: %s\n", len(sum.Successes), len(sum.Failures), utils.HumanizeBytes(sum.Freed))
+			switch removal {
+			case "trash":
+				fmt.Printf("Trashed: %d  Failed: %d  Moved to trash: %s\n", len(sum.Successes), len(sum.Failures), utils.HumanizeBytes(sum.Trashed))
+			case "quarantine":
+				fmt.Printf("Quarantined: %d  Failed: %d  Held: %s (node-module-man quarantine list)\n", len(sum.Successes), len(sum.Failures), utils.HumanizeBytes(sum.Quarantined))
+			default:
+				fmt.Printf("Deleted: %d  Failed: %d  Freed: %s\n", len(sum.Successes), len(sum.Failures), utils.HumanizeBytes(sum.Freed))
+			}
 			if len(sum.Failures) > 0 {
 				fmt.Println("Failures:")
 				for _, f := range sum.Failures {
@@ -149,11 +234,14 @@ func main() {
 		}
 		// Map to compressor targets
 		cts := make([]compressor.Target, 0, len(dt))
+		git := scanner.NewGitTracking()
 		for _, t := range dt {
-			cts = append(cts, compressor.Target{Path: t.Path, Size: t.Size})
+			protected := t.Protected || git.Tracked(t.Path)
+			cts = append(cts, compressor.Target{Path: t.Path, Size: t.Size, Files: t.Files, Protected: protected})
 		}
 		ctx := context.Background()
-		sum := compressor.CompressTargets(ctx, cts, compressor.Options{OutDir: outDir, Concurrency: concurrency, DeleteAfter: deleteAfter}, nil)
+		sum := compressor.CompressTargets(ctx, cts, compressor.Options{OutDir: outDir, Concurrency: concurrency, DeleteAfter: deleteAfter, Force: force}, nil)
+		recordJournal(journal.FromCompress(sum))
 		if jsonOut {
 			enc := json.NewEncoder(os.Stdout)
 			enc.SetIndent("", "  ")
@@ -163,6 +251,72 @@ func main() {
 			}
 		} else {
 			fmt.Printf("Compressed: %d  Failed: %d  Written: %s\n", len(sum.Successes), len(sum.Failures), utils.HumanizeBytes(sum.Written))
+			for _, s := range sum.Successes {
+				if s.SkippedLinks > 0 {
+					fmt.Printf("Note: %s: %d symlinks point outside it and were not archived\n", s.Path, s.SkippedLinks)
+				}
+			}
+			if len(sum.Failures) > 0 {
+				fmt.Println("Failures:")
+				for _, f := range sum.Failures {
+					fmt.Printf(" - %s: %v\n", f.Path, f.Err)
+				}
+			}
+		}
+		if len(sum.Failures) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Restore CLI mode via JSON input
+	if restoreJSON != "" || restoreStdin {
+		if !yesDelete {
+			fmt.Fprintln(os.Stderr, "--yes is required for non-interactive restore. Aborting.")
+			os.Exit(2)
+		}
+		var r io.Reader
+		if restoreStdin {
+			r = os.Stdin
+		} else {
+			f, err := os.Open(restoreJSON)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to open restore-json file: %v\n", err)
+				os.Exit(2)
+			}
+			defer f.Close()
+			r = f
+		}
+		dt, err := readDeleteTargets(r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid restore targets JSON: %v\n", err)
+			os.Exit(2)
+		}
+		archives := make([]string, 0, len(dt))
+		for _, t := range dt {
+			archives = append(archives, t.Path)
+		}
+		// archives the journal knows go back where they came from
+		ats, err := journal.Journal{}.ArchiveTargets(archives)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to read the operations journal: %v\n", err)
+			ats = ats[:0]
+			for _, a := range archives {
+				ats = append(ats, compressor.ArchiveTarget{Path: a})
+			}
+		}
+		ctx := context.Background()
+		sum := compressor.DecompressTargets(ctx, ats, compressor.DecompressOptions{Concurrency: concurrency, NoOverwrite: noOverwrite}, nil)
+		recordJournal(journal.FromDecompress(sum))
+		if jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(sum); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to write json: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			fmt.Printf("Restored: %d  Failed: %d  Extracted: %s\n", len(sum.Restored), len(sum.Failures), utils.HumanizeBytes(sum.Extracted))
 			if len(sum.Failures) > 0 {
 				fmt.Println("Failures:")
 				for _, f := range sum.Failures {
@@ -190,10 +344,45 @@ func main() {
 		MaxDepth:      maxDepth,
 		FollowSymlink: followLinks,
 		Excludes:      []string(excludes),
+		IgnoreFiles:   !noIgnore,
+		MaxEntries:    maxEntries,
+		DirTimeout:    dirTimeout,
+	}
+	if maxBytes != "" {
+		if opts.MaxBytes, err = utils.ParseBytes(maxBytes); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --max-bytes: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if !noCache {
+		if dir, err := scanner.DefaultCacheDir(); err == nil {
+			opts.CacheDir = dir
+			opts.RefreshCache = refresh
+		}
+	}
+	for _, t := range targetFlags {
+		specs, err := scanner.ParseTarget(t)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --target: %v\n", err)
+			os.Exit(2)
+		}
+		opts.Targets = append(opts.Targets, specs...)
+	}
+	if olderThan != "" {
+		if opts.OlderThan, err = utils.ParseAge(olderThan); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --older-than: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if newerThan != "" {
+		if opts.NewerThan, err = utils.ParseAge(newerThan); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --newer-than: %v\n", err)
+			os.Exit(2)
+		}
 	}
 
 	if useTUI {
-		if err := ui.Run(absRoot, opts, dryRun); err != nil {
+		if err := ui.Run(absRoot, opts, dryRun, force, removal); err != nil {
 			fmt.Fprintf(os.Stderr, "tui error: %v\n", err)
 			os.Exit(1)
 		}
@@ -202,49 +391,182 @@ func main() {
 
 	start := time.Now()
 
-	results, totalSize, scanErr := scanner.ScanNodeModules(ctx, absRoot, opts)
-	if scanErr != nil {
-		// We'll still print what we have but exit non-zero.
-		fmt.Fprintf(os.Stderr, "scan completed with errors: %v\n", scanErr)
+	results, totalSize, scanErrs := scanner.ScanNodeModules(ctx, absRoot, opts)
+	// We'll still print what we have but exit non-zero.
+	printScanErrors(scanErrs)
+
+	var totalReclaimable int64
+	var cache *cacheStats
+	if opts.CacheDir != "" {
+		cache = &cacheStats{}
+	}
+	for _, r := range results {
+		totalReclaimable += r.Reclaimable
+		if cache != nil {
+			if r.Cached {
+				cache.Hits++
+			} else {
+				cache.Misses++
+			}
+		}
 	}
 
 	if jsonOut {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
 		payload := struct {
-			Root      string               `json:"root"`
-			TotalSize int64                `json:"totalSize"`
-			Results   []scanner.ResultItem `json:"results"`
-			Duration  string               `json:"duration"`
-		}{Root: absRoot, TotalSize: totalSize, Results: results, Duration: time.Since(start).String()}
+			Root             string               `json:"root"`
+			TotalSize        int64                `json:"totalSize"`
+			TotalReclaimable int64                `json:"totalReclaimable"`
+			Results          []scanner.ResultItem `json:"results"`
+			Errors           []*scanner.ScanError `json:"errors"`
+			Cache            *cacheStats          `json:"cache,omitempty"`
+			Duration         string               `json:"duration"`
+		}{Root: absRoot, TotalSize: totalSize, TotalReclaimable: totalReclaimable, Results: results, Errors: scanErrs, Cache: cache, Duration: time.Since(start).String()}
+		if payload.Errors == nil {
+			payload.Errors = []*scanner.ScanError{}
+		}
 		if err := enc.Encode(payload); err != nil {
 			fmt.Fprintf(os.Stderr, "failed to write json: %v\n", err)
 			os.Exit(1)
 		}
 	} else {
 		fmt.Printf("node-module-man scan\nroot: %s\nfound: %d\n", absRoot, len(results))
+		fmt.Println("path\tkind\treclaimable\tapparent\tfiles\tage\tcommit\toffline\tproject")
 		fmt.Println("----------------------------------------------")
 		for _, r := range results {
-			sizeStr := utils.HumanizeBytes(r.Size)
+			sizeStr := utils.HumanizeBytes(r.Reclaimable) + "\t" + utils.HumanizeBytes(r.Size)
+			if r.Partial {
+				sizeStr = "≥ " + utils.HumanizeBytes(r.Reclaimable) + "\t≥ " + utils.HumanizeBytes(r.Size) + " (partial)"
+			}
+			sizeStr += "\t" + utils.HumanizeCount(r.Files)
+			project := projectLabel(r.Project)
+			if r.Health != scanner.HealthOK {
+				project += " [" + string(r.Health) + "]"
+			}
+			if r.Drift != nil && r.Drift.Status != scanner.DriftInSync {
+				project += " [" + string(r.Drift.Status) + "]"
+			}
+			age := ageLabel(r.LastActivity) + "\t" + commitLabel(r.Git) + "\t" + restoreLabel(r.Restore)
+			if r.Protected {
+				project += " [tracked in git]"
+			}
 			if r.Err != nil {
-				fmt.Printf("%s\t%s\t(ERROR: %v)\n", r.Path, sizeStr, r.Err)
+				fmt.Printf("%s\t%s\t%s\t%s\t%s\t(ERROR: %v)\n", r.Path, r.Kind, sizeStr, age, project, r.Err)
 			} else {
-				fmt.Printf("%s\t%s\n", r.Path, sizeStr)
+				fmt.Printf("%s\t%s\t%s\t%s\t%s\n", r.Path, r.Kind, sizeStr, age, project)
 			}
 		}
 		fmt.Println("----------------------------------------------")
-		fmt.Printf("Total size: %s\n", utils.HumanizeBytes(totalSize))
+		fmt.Printf("Total size: %s apparent, %s reclaimable\n", utils.HumanizeBytes(totalSize), utils.HumanizeBytes(totalReclaimable))
+		if cache != nil {
+			fmt.Printf("Cache: %d hits, %d misses\n", cache.Hits, cache.Misses)
+		}
 		fmt.Printf("Duration: %s\n", time.Since(start).Round(time.Millisecond))
 	}
 
-	if scanErr != nil {
+	if len(scanErrs) > 0 {
 		os.Exit(1)
 	}
 }
 
+// printScanErrors reports walk failures on stderr, grouped by kind.
+func printScanErrors(errs []*scanner.ScanError) {
+	if len(errs) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "scan completed with %d errors:\n", len(errs))
+	groups := scanner.GroupErrors(errs)
+	for _, kind := range scanner.ErrorKinds {
+		for _, e := range groups[kind] {
+			fmt.Fprintf(os.Stderr, " - [%s] %v\n", kind, e)
+		}
+	}
+}
+
+// commitLabel is the age of the last commit of the result's repository.
+func commitLabel(g *scanner.GitInfo) string {
+	if g == nil {
+		return "-"
+	}
+	return ageLabel(g.LastCommit)
+}
+
+// restoreLabel is the share of a node_modules restorable from local caches.
+func restoreLabel(r *scanner.Restorability) string {
+	switch {
+	case r == nil:
+		return "-"
+	case !r.Lockfile:
+		return "no lockfile"
+	}
+	return fmt.Sprintf("%d%% of %d", r.Percent, r.Packages)
+}
+
+// restorableTargets splits off the node_modules that cannot be reinstalled
+// fully from local caches. Other targets are build output and pass.
+func restorableTargets(targets []deleter.Target) ([]deleter.Target, []deleter.Failure) {
+	var keep []deleter.Target
+	var refused []deleter.Failure
+	for _, t := range targets {
+		if filepath.Base(t.Path) != scanner.KindNodeModules {
+			keep = append(keep, t)
+			continue
+		}
+		r := scanner.CheckRestorable(t.Path)
+		switch {
+		case !r.Lockfile:
+			refused = append(refused, deleter.Failure{Path: t.Path, Err: errors.New("not restorable offline: no lockfile")})
+		case r.Percent < 100 && r.Packages == 0:
+			refused = append(refused, deleter.Failure{Path: t.Path, Err: errors.New("not restorable offline: lockfile cannot be checked")})
+		case r.Percent < 100:
+			refused = append(refused, deleter.Failure{Path: t.Path, Err: fmt.Errorf("not restorable offline: %d of %d packages cached (%d%%)", r.Cached, r.Packages, r.Percent)})
+		default:
+			keep = append(keep, t)
+		}
+	}
+	return keep, refused
+}
+
+// cacheStats counts results served from the persistent size index.
+type cacheStats struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// projectLabel renders project metadata for the table output, e.g.
+// "my-app@1.2.0 (pnpm, private)".
+func projectLabel(p scanner.ProjectInfo) string {
+	if !p.HasPackageJSON && p.Lockfile == scanner.LockNone {
+		return "-"
+	}
+	var tags []string
+	if p.Lockfile != scanner.LockNone {
+		tags = append(tags, string(p.Lockfile))
+	}
+	if p.Private {
+		tags = append(tags, "private")
+	}
+	if len(p.Workspaces) > 0 {
+		tags = append(tags, "workspaces")
+	}
+	if len(tags) == 0 {
+		return p.DisplayName()
+	}
+	return fmt.Sprintf("%s (%s)", p.DisplayName(), strings.Join(tags, ", "))
+}
+
+// ageLabel renders time since the last project activity, or "-" when unknown.
+func ageLabel(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return utils.HumanizeAge(time.Since(t))
+}
+
 // readDeleteTargets is flexible with input schema:
 // - ["/path/one", "/path/two"]
-// - [{"path":"/p","size":123}, ...]
+// - [{"path":"/p","size":123,"files":4567,"protected":true}, ...]
 // - {"targets":[ ...either of above... ]}
 func readDeleteTargets(r io.Reader) ([]deleter.Target, error) {
 	dec := json.NewDecoder(r)
@@ -264,13 +586,17 @@ func readDeleteTargets(r io.Reader) ([]deleter.Target, error) {
 					res = append(res, deleter.Target{Path: ee})
 				case map[string]interface{}:
 					p, _ := ee["path"].(string)
-					var size int64
+					var size, files int64
 					switch vv := ee["size"].(type) {
 					case float64:
 						size = int64(vv)
 					}
+					if vv, ok := ee["files"].(float64); ok {
+						files = int64(vv)
+					}
+					protected, _ := ee["protected"].(bool)
 					if p != "" {
-						res = append(res, deleter.Target{Path: p, Size: size})
+						res = append(res, deleter.Target{Path: p, Size: size, Files: files, Protected: protected})
 					}
 				default:
 					// ignore unknown entries
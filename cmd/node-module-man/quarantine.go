@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"node-module-man/internal/deleter"
+	"node-module-man/internal/journal"
+	"node-module-man/pkg/utils"
+)
+
+// runQuarantine implements `node-module-man quarantine list|restore|purge`:
+// manage what --quarantine and the TUI's quarantine mode set aside.
+func runQuarantine(args []string) int {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "usage: node-module-man quarantine list [--json]\n       node-module-man quarantine restore <id>\n       node-module-man quarantine purge --older-than 7d [--dry-run] [--json]")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	fs := flag.NewFlagSet("quarantine "+args[0], flag.ExitOnError)
+	var (
+		jsonOut   bool
+		olderThan string
+		dryRun    bool
+	)
+	fs.BoolVar(&jsonOut, "json", false, "Output JSON instead of table")
+	if args[0] == "purge" {
+		fs.StringVar(&olderThan, "older-than", "", "Purge entries quarantined at least this long ago (e.g. 7d, 36h); required")
+		fs.BoolVar(&dryRun, "dry-run", false, "Report what would be purged without deleting")
+		fs.BoolVar(&dryRun, "d", false, "Alias of --dry-run")
+	}
+	_ = fs.Parse(args[1:])
+	q := deleter.Quarantine{}
+
+	switch args[0] {
+	case "list":
+		entries, err := q.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read the quarantine: %v\n", err)
+			return 1
+		}
+		if jsonOut {
+			return encodeJSON(entries)
+		}
+		var total int64
+		fmt.Println("id\tage\tsize\tpath")
+		fmt.Println("----------------------------------------------")
+		for _, e := range entries {
+			total += e.Size
+			fmt.Printf("%s\t%s\t%s\t%s\n", e.ID, utils.HumanizeAge(time.Since(e.Time)), utils.HumanizeBytes(e.Size), e.Path)
+		}
+		fmt.Println("----------------------------------------------")
+		fmt.Printf("Quarantined: %d  Held: %s\n", len(entries), utils.HumanizeBytes(total))
+		return 0
+	case "restore":
+		if fs.NArg() != 1 {
+			usage()
+			return 2
+		}
+		e, err := q.Restore(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to restore %s: %v\n", fs.Arg(0), err)
+			return 1
+		}
+		if jsonOut {
+			return encodeJSON(e)
+		}
+		fmt.Printf("Restored %s (%s)\n", e.Path, utils.HumanizeBytes(e.Size))
+		return 0
+	case "purge":
+		if olderThan == "" {
+			fmt.Fprintln(os.Stderr, "--older-than is required to purge, e.g. --older-than 7d (0s purges everything).")
+			return 2
+		}
+		age, err := utils.ParseAge(olderThan)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --older-than: %v\n", err)
+			return 2
+		}
+		purged, failures, err := q.Purge(time.Now().Add(-age), dryRun)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to purge the quarantine: %v\n", err)
+			return 1
+		}
+		if !dryRun {
+			recordJournal(journal.FromPurge(purged))
+		}
+		var freed int64
+		for _, e := range purged {
+			freed += e.Size
+		}
+		if jsonOut {
+			payload := struct {
+				Purged   []deleter.QuarantineEntry `json:"purged"`
+				Freed    int64                     `json:"freed"`
+				Failures []deleter.Failure         `json:"failures"`
+				DryRun   bool                      `json:"dryRun,omitempty"`
+			}{Purged: purged, Freed: freed, Failures: failures, DryRun: dryRun}
+			if payload.Purged == nil {
+				payload.Purged = []deleter.QuarantineEntry{}
+			}
+			if payload.Failures == nil {
+				payload.Failures = []deleter.Failure{}
+			}
+			if code := encodeJSON(payload); code != 0 {
+				return code
+			}
+		} else {
+			verb := "Purged"
+			if dryRun {
+				verb = "Would purge"
+			}
+			fmt.Printf("%s: %d  Freed: %s  Failed: %d\n", verb, len(purged), utils.HumanizeBytes(freed), len(failures))
+			for _, f := range failures {
+				fmt.Printf(" - %s: %v\n", f.Path, f.Err)
+			}
+		}
+		if len(failures) > 0 {
+			return 1
+		}
+		return 0
+	}
+	usage()
+	return 2
+}
+
+// encodeJSON writes v to stdout, indented.
+func encodeJSON(v interface{}) int {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write json: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"node-module-man/internal/compressor"
+	"node-module-man/internal/journal"
+	"node-module-man/pkg/utils"
+)
+
+// restoreFailure is the JSON form of a path that could not be restored.
+type restoreFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// runRestore implements `node-module-man restore <path>...`: look up the
+// newest archive the journal records for each node_modules (or for each
+// archived directory of a project) and extract it back in place.
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	var (
+		jsonOut   bool
+		dryRun    bool
+		overwrite bool
+	)
+	fs.BoolVar(&jsonOut, "json", false, "Output JSON instead of text")
+	fs.BoolVar(&overwrite, "overwrite", false, "Replace a directory that exists at the original path")
+	fs.BoolVar(&dryRun, "dry-run", false, "Report which archives would be restored without extracting")
+	fs.BoolVar(&dryRun, "d", false, "Alias of --dry-run")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: node-module-man restore [--dry-run] [--overwrite] [--json] <project or node_modules path>...")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	j := journal.Journal{}
+	restored := []journal.Entry{}
+	failures := []restoreFailure{}
+	var found []journal.Entry
+	for _, p := range fs.Args() {
+		entries, err := j.Archives(p)
+		if err != nil {
+			failures = append(failures, restoreFailure{Path: p, Error: err.Error()})
+			continue
+		}
+		found = append(found, entries...)
+	}
+	if dryRun {
+		restored = append(restored, found...)
+	} else {
+		targets := make([]compressor.ArchiveTarget, 0, len(found))
+		dirs := map[string]string{}
+		for _, e := range found {
+			targets = append(targets, e.ArchiveTarget())
+			dirs[e.Archive] = e.Path
+		}
+		sum := compressor.DecompressTargets(context.Background(), targets, compressor.DecompressOptions{NoOverwrite: !overwrite}, nil)
+		done := journal.FromDecompress(sum)
+		recordJournal(done)
+		restored = append(restored, done...)
+		for _, f := range sum.Failures {
+			failures = append(failures, restoreFailure{Path: dirs[f.Path], Error: f.Err.Error()})
+		}
+	}
+
+	if jsonOut {
+		payload := struct {
+			Restored []journal.Entry  `json:"restored"`
+			Failures []restoreFailure `json:"failures"`
+			DryRun   bool             `json:"dryRun,omitempty"`
+		}{Restored: restored, Failures: failures, DryRun: dryRun}
+		if code := encodeJSON(payload); code != 0 {
+			return code
+		}
+	} else {
+		verb := "Restored"
+		if dryRun {
+			verb = "Would restore"
+		}
+		for _, e := range restored {
+			fmt.Printf("%s %s (%s) from %s\n", verb, e.Path, utils.HumanizeBytes(e.Size), e.Archive)
+		}
+		for _, f := range failures {
+			fmt.Printf(" - %s: %s\n", f.Path, f.Error)
+		}
+	}
+	if len(failures) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// recordJournal appends entries to the operations journal. A journal that
+// cannot be written is reported but does not fail the run.
+func recordJournal(entries []journal.Entry) {
+	if err := (journal.Journal{}).Record(entries...); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update the operations journal: %v\n", err)
+	}
+}
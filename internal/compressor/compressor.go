@@ -3,6 +3,8 @@ package compressor
 import (
     "archive/zip"
     "context"
+    "crypto/sha256"
+    "encoding/hex"
     "errors"
     "fmt"
     "io"
@@ -10,11 +12,18 @@ import (
     "os"
     "path/filepath"
     "strings"
+
+    "node-module-man/internal/deleter"
 )
 
 type Target struct {
-    Path string
-    Size int64
+    Path  string
+    Size  int64
+    Files int64 // files in the tree when known; drives the ETA
+
+    // Protected sources, such as a node_modules vendored into git, are
+    // archived but kept by DeleteAfter unless Options.Force is set.
+    Protected bool
 }
 
 type Progress struct {
@@ -24,12 +33,26 @@ type Progress struct {
     Dest         string
     BytesWritten int64
     Err          error
+
+    // FilesDone counts files archived so far across all targets, FilesTotal
+    // sums Target.Files; FilesTotal is zero when the counts are unknown.
+    FilesDone  int64
+    FilesTotal int64
 }
 
 type Success struct {
     Path string
     Dest string
     Size int64 // archive size in bytes
+
+    SourceSize  int64  // Target.Size of the archived directory
+    SourceFiles int64  // Target.Files
+    Checksum    string // sha256 of the archive, hex-encoded
+    Removed     bool   // DeleteAfter removed the source
+
+    // SkippedLinks counts symlinks left out of the archive because they
+    // point outside the archived directory, such as workspace links.
+    SkippedLinks int
 }
 
 type Failure struct {
@@ -47,6 +70,7 @@ type Options struct {
     OutDir      string
     Concurrency int
     DeleteAfter bool
+    Force       bool // let DeleteAfter remove protected sources too
 }
 
 // CompressTargets creates one .zip archive per target directory.
@@ -54,6 +78,10 @@ type Options struct {
 func CompressTargets(ctx context.Context, targets []Target, opts Options, progress chan<- Progress) Summary {
     sum := Summary{Successes: make([]Success, 0, len(targets))}
     total := len(targets)
+    var filesTotal, filesDone int64
+    for _, t := range targets {
+        filesTotal += t.Files
+    }
     for i, t := range targets {
         select {
         case <-ctx.Done():
@@ -100,9 +128,10 @@ func CompressTargets(ctx context.Context, targets []Target, opts Options, progre
         // Avoid overwrites
         dest = nextAvailable(dest)
 
-        written, err := zipDirectory(ctx, src, dest, func(rel string, bytes int64) {
+        written, checksum, skipped, err := zipDirectory(ctx, src, dest, func(rel string, bytes int64) {
+            filesDone++
             if progress != nil {
-                progress <- Progress{Completed: i, Total: total, Path: filepath.Join(src, rel), Dest: dest, BytesWritten: bytes}
+                progress <- Progress{Completed: i, Total: total, Path: filepath.Join(src, rel), Dest: dest, BytesWritten: bytes, FilesDone: filesDone, FilesTotal: filesTotal}
             }
         })
         if err != nil {
@@ -113,17 +142,21 @@ func CompressTargets(ctx context.Context, targets []Target, opts Options, progre
             continue
         }
 
-        // Optionally delete source after success
+        s := Success{Path: src, Dest: dest, Size: written, SourceSize: t.Size, SourceFiles: t.Files, Checksum: checksum, SkippedLinks: skipped}
+        // Optionally delete source after success, with the deleter's
+        // protection rules
         if opts.DeleteAfter {
-            if rmErr := os.RemoveAll(src); rmErr != nil {
+            del := deleter.DeleteTargets(ctx, []deleter.Target{{Path: src, Size: t.Size, Files: t.Files, Protected: t.Protected}}, deleter.Options{Concurrency: 1, Force: opts.Force}, nil)
+            for _, f := range del.Failures {
                 // Keep success but record failure as warning
-                sum.Failures = append(sum.Failures, Failure{Path: src, Err: fmt.Errorf("delete-after failed: %w", rmErr)})
+                sum.Failures = append(sum.Failures, Failure{Path: src, Err: fmt.Errorf("delete-after failed: %w", f.Err)})
             }
+            s.Removed = len(del.Successes) > 0
         }
 
-        sum.Successes = append(sum.Successes, Success{Path: src, Dest: dest, Size: written})
+        sum.Successes = append(sum.Successes, s)
         sum.Written += written
-        if progress != nil { progress <- Progress{Completed: i + 1, Total: total, Path: src, Dest: dest, BytesWritten: written} }
+        if progress != nil { progress <- Progress{Completed: i + 1, Total: total, Path: src, Dest: dest, BytesWritten: written, FilesDone: filesDone, FilesTotal: filesTotal} }
     }
     return sum
 }
@@ -145,19 +178,25 @@ func nextAvailable(p string) string {
     return p
 }
 
-// zipDirectory zips directory src into dest path. Returns final archive size.
+// zipDirectory zips directory src into dest path. Returns final archive size,
+// its sha256 checksum and the number of symlinks skipped.
+// Entries keep their Unix permission bits; symlinks are stored as symlink
+// entries holding their target when it resolves inside src (absolute targets
+// are made relative), and skipped otherwise.
 // progressCb is called after each file is written with the relative path and current bytes written.
-func zipDirectory(ctx context.Context, src, dest string, progressCb func(rel string, bytes int64)) (int64, error) {
+func zipDirectory(ctx context.Context, src, dest string, progressCb func(rel string, bytes int64)) (int64, string, int, error) {
     f, err := os.Create(dest)
-    if err != nil { return 0, err }
+    if err != nil { return 0, "", 0, err }
     defer func() { _ = f.Close() }()
 
-    zw := zip.NewWriter(f)
+    h := sha256.New()
+    zw := zip.NewWriter(io.MultiWriter(f, h))
     defer func() { _ = zw.Close() }()
 
     // Walk the directory and add files
     prefix := filepath.Base(src)
     var totalWritten int64
+    skipped := 0
     err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
         if err != nil { return err }
         rel, err := filepath.Rel(src, path)
@@ -175,16 +214,29 @@ func zipDirectory(ctx context.Context, src, dest string, progressCb func(rel str
         info, err := d.Info()
         if err != nil { return err }
 
-        // Skip symlinks to avoid reading directories or external targets
-        if info.Mode() & os.ModeSymlink != 0 {
-            return nil
-        }
-
-        // Create header
+        // Create header; it records the Unix mode, symlink type included
         hdr, err := zip.FileInfoHeader(info)
         if err != nil { return err }
         // Force a top-level directory prefix and forward slashes in zip
         hdr.Name = filepath.ToSlash(filepath.Join(prefix, rel))
+
+        // Store symlinks (pnpm's layout, .bin links) with their target
+        // rather than following them out of the tree
+        if info.Mode() & os.ModeSymlink != 0 {
+            target, err := os.Readlink(path)
+            if err != nil { return err }
+            target, ok := archiveLink(src, path, rel, target)
+            if !ok {
+                skipped++
+                return nil
+            }
+            hdr.Method = zip.Store
+            w, err := zw.CreateHeader(hdr)
+            if err != nil { return err }
+            _, err = io.WriteString(w, target)
+            return err
+        }
+
         if d.IsDir() {
             hdr.Name += "/"
         } else {
@@ -209,11 +261,39 @@ func zipDirectory(ctx context.Context, src, dest string, progressCb func(rel str
         }
         return nil
     })
-    if err != nil { return 0, err }
+    if err != nil { return 0, "", 0, err }
 
-    if err := zw.Close(); err != nil { return 0, err }
-    if err := f.Sync(); err != nil { return 0, err }
+    if err := zw.Close(); err != nil { return 0, "", 0, err }
+    if err := f.Sync(); err != nil { return 0, "", 0, err }
     st, err := os.Stat(dest)
-    if err != nil { return 0, err }
-    return st.Size(), nil
+    if err != nil { return 0, "", 0, err }
+    return st.Size(), hex.EncodeToString(h.Sum(nil)), skipped, nil
 }
+
+// archiveLink returns the target to store for the symlink at path, rel
+// below the archived directory src: relative, slash-separated, and resolving
+// inside the archive, other links on the way included. ok is false for a
+// link that leaves it.
+func archiveLink(src, path, rel, target string) (string, bool) {
+    if filepath.IsAbs(target) {
+        r, err := filepath.Rel(filepath.Dir(path), target)
+        if err != nil { return "", false }
+        target = r
+    }
+    if !linkInside(src, rel, target) {
+        return "", false
+    }
+    return filepath.ToSlash(target), true
+}
+
+// Checksum returns the sha256 of the file at path, hex-encoded like
+// Success.Checksum.
+func Checksum(path string) (string, error) {
+    f, err := os.Open(path)
+    if err != nil { return "", err }
+    defer f.Close()
+    h := sha256.New()
+    if _, err := io.Copy(h, f); err != nil { return "", err }
+    return hex.EncodeToString(h.Sum(nil)), nil
+}
+
@@ -0,0 +1,254 @@
+package compressor
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// pnpmFixture lays out a pnpm-style node_modules under project: packages in
+// .pnpm linked from the top level and from each other, an executable
+// behind a .bin link, a read-only file and a workspace link that leaves
+// the tree.
+func pnpmFixture(t *testing.T, project string) string {
+	t.Helper()
+	nm := filepath.Join(project, "node_modules")
+	files := []struct {
+		rel  string
+		data string
+		perm fs.FileMode
+	}{
+		{".modules.yaml", "layoutVersion: 5\n", 0o644},
+		{".pnpm/lodash@4.17.21/node_modules/lodash/package.json", `{"name":"lodash","version":"4.17.21"}`, 0o644},
+		{".pnpm/lodash@4.17.21/node_modules/lodash/index.js", "module.exports = {}\n", 0o644},
+		{".pnpm/foo@1.0.0/node_modules/foo/package.json", `{"name":"foo","version":"1.0.0","bin":"bin/foo.js"}`, 0o644},
+		{".pnpm/foo@1.0.0/node_modules/foo/bin/foo.js", "#!/usr/bin/env node\n", 0o755},
+		{".pnpm/foo@1.0.0/node_modules/foo/LICENSE", "MIT\n", 0o400},
+	}
+	for _, f := range files {
+		p := filepath.Join(nm, filepath.FromSlash(f.rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(f.data), f.perm); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := os.Chmod(p, f.perm); err != nil {
+			t.Fatalf("chmod: %v", err)
+		}
+	}
+	links := map[string]string{
+		"lodash":                              ".pnpm/lodash@4.17.21/node_modules/lodash",
+		"foo":                                 ".pnpm/foo@1.0.0/node_modules/foo",
+		".pnpm/foo@1.0.0/node_modules/lodash": "../../lodash@4.17.21/node_modules/lodash",
+		".bin/foo":                            "../foo/bin/foo.js",
+		"workspace-pkg":                       "../../packages/pkg",
+	}
+	for rel, target := range links {
+		p := filepath.Join(nm, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.Symlink(filepath.FromSlash(target), p); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+	return nm
+}
+
+// snapshot describes every entry below root by type, permission bits and
+// content or link target.
+func snapshot(t *testing.T, root string) map[string]string {
+	t.Helper()
+	out := map[string]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			out[rel] = "link " + filepath.ToSlash(target)
+		case info.IsDir():
+			out[rel] = fmt.Sprintf("dir %v", info.Mode().Perm())
+		default:
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			out[rel] = fmt.Sprintf("file %v %q", info.Mode().Perm(), b)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("snapshot %s: %v", root, err)
+	}
+	return out
+}
+
+func TestCompressDecompress_RoundTripsPNPMLayout(t *testing.T) {
+	project := filepath.Join(t.TempDir(), "app")
+	nm := pnpmFixture(t, project)
+	// an absolute link into the tree is stored relative to its directory
+	if err := os.Symlink(filepath.Join(nm, "lodash"), filepath.Join(nm, "lodash-abs")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	want := snapshot(t, nm)
+	delete(want, "workspace-pkg") // leaves the archive: skipped
+	want["lodash-abs"] = "link lodash"
+
+	sum := CompressTargets(context.Background(), []Target{{Path: nm}}, Options{DeleteAfter: true}, nil)
+	if len(sum.Failures) != 0 || len(sum.Successes) != 1 {
+		t.Fatalf("compress = %+v", sum)
+	}
+	s := sum.Successes[0]
+	if !s.Removed || s.SkippedLinks != 1 {
+		t.Fatalf("success = %+v, want the source removed and 1 skipped link", s)
+	}
+	if _, err := os.Lstat(nm); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("source still present after delete-after: %v", err)
+	}
+
+	rs := DecompressTargets(context.Background(), []ArchiveTarget{{Path: s.Dest, Checksum: s.Checksum}}, DecompressOptions{NoOverwrite: true}, nil)
+	if len(rs.Failures) != 0 || len(rs.Restored) != 1 || rs.Restored[0].Path != nm {
+		t.Fatalf("decompress = %+v", rs)
+	}
+	if got := snapshot(t, nm); !reflect.DeepEqual(got, want) {
+		for rel, w := range want {
+			if got[rel] != w {
+				t.Errorf("%s: got %q, want %q", rel, got[rel], w)
+			}
+		}
+		for rel, g := range got {
+			if _, ok := want[rel]; !ok {
+				t.Errorf("%s: unexpected %q", rel, g)
+			}
+		}
+	}
+	// the restored links resolve
+	if b, err := os.ReadFile(filepath.Join(nm, "foo", "bin", "foo.js")); err != nil || string(b) != "#!/usr/bin/env node\n" {
+		t.Fatalf("read through .pnpm link: %q, %v", b, err)
+	}
+	if st, err := os.Stat(filepath.Join(nm, ".bin", "foo")); err != nil || st.Mode().Perm()&0o111 == 0 {
+		t.Fatalf(".bin/foo does not resolve to an executable: %v, %v", st, err)
+	}
+
+	// restoring again refuses the existing tree, or replaces it
+	rs = DecompressTargets(context.Background(), []ArchiveTarget{{Path: s.Dest}}, DecompressOptions{NoOverwrite: true}, nil)
+	if len(rs.Failures) != 1 || !errors.Is(rs.Failures[0].Err, ErrExists) {
+		t.Fatalf("no-overwrite restore failures = %v, want ErrExists", rs.Failures)
+	}
+	if err := os.WriteFile(filepath.Join(nm, "stray.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	rs = DecompressTargets(context.Background(), []ArchiveTarget{{Path: s.Dest}}, DecompressOptions{}, nil)
+	if len(rs.Failures) != 0 {
+		t.Fatalf("overwrite restore failures = %v", rs.Failures)
+	}
+	if got := snapshot(t, nm); !reflect.DeepEqual(got, want) {
+		t.Fatalf("replaced tree differs from the original:\n got %v\nwant %v", got, want)
+	}
+	if _, err := os.Lstat(filepath.Join(nm, "stray.txt")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("replaced tree kept stray.txt: %v", err)
+	}
+
+	// a tampered archive fails its checksum
+	if err := os.WriteFile(s.Dest, []byte("not a zip"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	rs = DecompressTargets(context.Background(), []ArchiveTarget{{Path: s.Dest, Checksum: s.Checksum}}, DecompressOptions{}, nil)
+	if len(rs.Failures) != 1 || !errors.Is(rs.Failures[0].Err, ErrChecksum) {
+		t.Fatalf("tampered restore failures = %v, want ErrChecksum", rs.Failures)
+	}
+}
+
+// zipEntry is one entry of a hand-built archive.
+type zipEntry struct {
+	name string
+	mode fs.FileMode
+	body string // file content or link target
+}
+
+func writeZip(t *testing.T, path string, entries []zipEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Store}
+		hdr.SetMode(e.mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("create header %s: %v", e.name, err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatalf("write %s: %v", e.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
+
+func TestDecompressTargets_RefusesHostileArchives(t *testing.T) {
+	file := func(name string) zipEntry { return zipEntry{name: name, mode: 0o644, body: "x"} }
+	link := func(name, target string) zipEntry {
+		return zipEntry{name: name, mode: fs.ModeSymlink | 0o777, body: target}
+	}
+	cases := []struct {
+		name    string
+		entries []zipEntry
+	}{
+		{"parent dir", []zipEntry{file("../x")}},
+		{"absolute name", []zipEntry{file("/abs")}},
+		{"climbs out of the prefix", []zipEntry{file("node_modules/a"), file("node_modules/../../x")}},
+		{"second top-level dir", []zipEntry{file("node_modules/a"), file("other/b")}},
+		{"file through an extracted symlink dir", []zipEntry{link("node_modules/l", "."), file("node_modules/l/f")}},
+		{"absolute link target", []zipEntry{link("node_modules/l", "/etc")}},
+		{"link climbing out of the tree", []zipEntry{link("node_modules/l", "../x")}},
+		{"chained .. through another link", []zipEntry{link("node_modules/a/s", ".."), link("node_modules/l", "a/s/..")}},
+		{"chained link extracted first", []zipEntry{link("node_modules/l", "a/s/../.."), link("node_modules/a/s", "..")}},
+		{"duplicate name", []zipEntry{file("node_modules/f"), file("node_modules/f")}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archive := filepath.Join(dir, "node_modules.zip")
+			writeZip(t, archive, tc.entries)
+			rs := DecompressTargets(context.Background(), []ArchiveTarget{{Path: archive}}, DecompressOptions{}, nil)
+			if len(rs.Failures) != 1 || len(rs.Restored) != 0 {
+				t.Fatalf("decompress = %+v, want one failure", rs)
+			}
+			names, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("readdir: %v", err)
+			}
+			if len(names) != 1 || names[0].Name() != "node_modules.zip" {
+				var left []string
+				for _, n := range names {
+					left = append(left, n.Name())
+				}
+				t.Fatalf("left behind: %v", left)
+			}
+		})
+	}
+}
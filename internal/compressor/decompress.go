@@ -0,0 +1,392 @@
+package compressor
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrExists is the failure for an archive whose directory already exists
+// when DecompressOptions.NoOverwrite is set.
+var ErrExists = errors.New("destination exists; not overwriting")
+
+// ErrChecksum is the failure for an archive that does not match
+// ArchiveTarget.Checksum.
+var ErrChecksum = errors.New("archive changed since it was written")
+
+// ArchiveTarget is an archive written by CompressTargets to restore.
+type ArchiveTarget struct {
+	Path string
+	Dir  string // parent to restore the archived directory into; "" for the archive's directory
+
+	// Checksum is the sha256 (hex) the archive must match, as recorded in
+	// Success.Checksum; "" skips the check.
+	Checksum string
+}
+
+// DecompressOptions controls DecompressTargets.
+type DecompressOptions struct {
+	Concurrency int
+
+	// NoOverwrite refuses to restore over an existing directory. Without
+	// it an existing directory is replaced, once the archive has been
+	// extracted in full next to it.
+	NoOverwrite bool
+}
+
+// Restored is one archive extracted by DecompressTargets.
+type Restored struct {
+	Archive string
+	Path    string // the restored directory
+	Size    int64  // bytes extracted
+	Files   int64
+}
+
+// RestoreSummary is the result of DecompressTargets. Failures carry the
+// archive paths.
+type RestoreSummary struct {
+	Restored  []Restored
+	Failures  []Failure
+	Extracted int64 // total bytes extracted
+}
+
+// DecompressTargets restores each archive's directory into its parent,
+// the reverse of CompressTargets. An archive is extracted into a temporary
+// sibling first and renamed into place, so a failure leaves nothing
+// behind. Entry names are confined to the archived directory, symlinks are
+// recreated only when their targets stay inside it, and Unix permission
+// bits are restored. Progress events carry the archive as Path and the
+// restored directory as Dest.
+func DecompressTargets(ctx context.Context, targets []ArchiveTarget, opts DecompressOptions, progress chan<- Progress) RestoreSummary {
+	sum := RestoreSummary{Restored: make([]Restored, 0, len(targets))}
+	total := len(targets)
+	var filesDone int64
+	fail := func(i int, path string, err error) {
+		if progress != nil {
+			progress <- Progress{Completed: i + 1, Total: total, Path: path, Err: err}
+		}
+		sum.Failures = append(sum.Failures, Failure{Path: path, Err: err})
+	}
+	for i, t := range targets {
+		if err := ctx.Err(); err != nil {
+			for j := i; j < len(targets); j++ {
+				fail(j, targets[j].Path, err)
+			}
+			return sum
+		}
+		if t.Checksum != "" {
+			got, err := Checksum(t.Path)
+			if err == nil && got != t.Checksum {
+				err = fmt.Errorf("%w: sha256 %s, expected %s", ErrChecksum, got, t.Checksum)
+			}
+			if err != nil {
+				fail(i, t.Path, err)
+				continue
+			}
+		}
+		dir := t.Dir
+		if dir == "" {
+			dir = filepath.Dir(t.Path)
+		}
+		r, err := unzipDirectory(ctx, t.Path, dir, opts.NoOverwrite, func(dest string, bytes int64) {
+			filesDone++
+			if progress != nil {
+				progress <- Progress{Completed: i, Total: total, Path: t.Path, Dest: dest, BytesWritten: bytes, FilesDone: filesDone}
+			}
+		})
+		if err != nil {
+			fail(i, t.Path, err)
+			continue
+		}
+		sum.Restored = append(sum.Restored, r)
+		sum.Extracted += r.Size
+		if progress != nil {
+			progress <- Progress{Completed: i + 1, Total: total, Path: t.Path, Dest: r.Path, BytesWritten: r.Size, FilesDone: filesDone}
+		}
+	}
+	return sum
+}
+
+// unzipDirectory extracts the archive written by zipDirectory into dir.
+// progressCb is called after each file with the restored directory and the
+// bytes extracted so far.
+func unzipDirectory(ctx context.Context, archive, dir string, noOverwrite bool, progressCb func(dest string, bytes int64)) (Restored, error) {
+	res := Restored{Archive: archive}
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return res, err
+	}
+	defer zr.Close()
+
+	// every entry lives below one top-level directory, the archived one
+	prefix := ""
+	rels := make([]string, len(zr.File))
+	for i, zf := range zr.File {
+		top, rel, err := splitEntry(zf.Name)
+		if err != nil {
+			return res, err
+		}
+		if prefix == "" {
+			prefix = top
+		} else if top != prefix {
+			return res, fmt.Errorf("not a node-module-man archive: entries below both %s and %s", prefix, top)
+		}
+		rels[i] = rel
+	}
+	if prefix == "" {
+		return res, fmt.Errorf("empty archive: %s", archive)
+	}
+	res.Path = filepath.Join(dir, prefix)
+	if _, err := os.Lstat(res.Path); err == nil && noOverwrite {
+		return res, fmt.Errorf("%s: %w", res.Path, ErrExists)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return res, err
+	}
+	staging, err := os.MkdirTemp(dir, "."+prefix+".restore-")
+	if err != nil {
+		return res, err
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+
+	dirModes := map[string]fs.FileMode{".": 0o755}
+	var links []string
+	for i, zf := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		rel := rels[i]
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			if err := ensureDir(staging, rel, dirModes); err != nil {
+				return res, err
+			}
+			dirModes[rel] = permOr(mode, 0o755)
+		case mode&fs.ModeSymlink != 0:
+			if err := ensureDir(staging, filepath.Dir(rel), dirModes); err != nil {
+				return res, err
+			}
+			if err := extractSymlink(zf, staging, rel); err != nil {
+				return res, err
+			}
+			links = append(links, rel)
+		case mode.IsRegular():
+			if err := ensureDir(staging, filepath.Dir(rel), dirModes); err != nil {
+				return res, err
+			}
+			n, err := extractFile(zf, filepath.Join(staging, rel), permOr(mode, 0o644))
+			res.Size += n
+			if err != nil {
+				return res, err
+			}
+			res.Files++
+			if progressCb != nil {
+				progressCb(res.Path, res.Size)
+			}
+		default:
+			return res, fmt.Errorf("unsupported entry in archive: %s (%s)", zf.Name, mode.Type())
+		}
+	}
+
+	// with every link in place, check that none leads out of the tree
+	// through others, such as a/s -> .. and l -> a/s/..
+	for _, rel := range links {
+		target, err := os.Readlink(filepath.Join(staging, rel))
+		if err != nil {
+			return res, err
+		}
+		if !linkInside(staging, rel, target) {
+			return res, fmt.Errorf("unsafe symlink in archive: %s -> %s", filepath.ToSlash(filepath.Join(prefix, rel)), target)
+		}
+	}
+
+	// directory modes last, deepest first, so read-only ones do not get in
+	// the way of their contents
+	dirs := make([]string, 0, len(dirModes))
+	for rel := range dirModes {
+		dirs = append(dirs, rel)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	for _, rel := range dirs {
+		if err := os.Chmod(filepath.Join(staging, rel), dirModes[rel]); err != nil {
+			return res, err
+		}
+	}
+
+	if _, err := os.Lstat(res.Path); err == nil {
+		if noOverwrite {
+			return res, fmt.Errorf("%s: %w", res.Path, ErrExists)
+		}
+		// move the old tree aside first so it is never half replaced
+		old := staging + "-replaced"
+		if err := os.Rename(res.Path, old); err != nil {
+			return res, err
+		}
+		if err := os.Rename(staging, res.Path); err != nil {
+			_ = os.Rename(old, res.Path)
+			return res, err
+		}
+		return res, os.RemoveAll(old)
+	}
+	return res, os.Rename(staging, res.Path)
+}
+
+// splitEntry splits an entry name into its top-level directory and the
+// path below it, refusing absolute names and names that climb out of the
+// archive ("zip slip").
+func splitEntry(name string) (top, rel string, err error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" || clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("unsafe path in archive: %s", name)
+	}
+	top, rel, _ = strings.Cut(clean, string(filepath.Separator))
+	if rel == "" {
+		rel = "."
+	}
+	return top, rel, nil
+}
+
+// ensureDir creates the directory rel below root and its parents. Each
+// one must be a real directory: an entry may not be written through a
+// symlink extracted before it.
+func ensureDir(root, rel string, known map[string]fs.FileMode) error {
+	if _, ok := known[rel]; ok {
+		return nil
+	}
+	if err := ensureDir(root, filepath.Dir(rel), known); err != nil {
+		return err
+	}
+	p := filepath.Join(root, rel)
+	st, err := os.Lstat(p)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := os.Mkdir(p, 0o700); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !st.IsDir():
+		return fmt.Errorf("unsafe path in archive: %s is not a directory", rel)
+	}
+	known[rel] = 0o755
+	return nil
+}
+
+// extractSymlink recreates a symlink entry. Its target must be relative;
+// unzipDirectory checks where it leads once all links are extracted.
+func extractSymlink(zf *zip.File, root, rel string) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return err
+	}
+	target := string(b)
+	if !relativeTarget(target) {
+		return fmt.Errorf("unsafe symlink in archive: %s -> %s", zf.Name, target)
+	}
+	return os.Symlink(target, filepath.Join(root, rel))
+}
+
+func relativeTarget(target string) bool {
+	return target != "" && !filepath.IsAbs(target) && filepath.VolumeName(target) == "" && !strings.HasPrefix(target, "/")
+}
+
+// maxLinkHops bounds the symlinks followed while resolving one link, like
+// the kernel's ELOOP limit.
+const maxLinkHops = 40
+
+// linkInside reports whether the symlink at rel below root, pointing to
+// target, resolves inside root. Symlinks met on the way are followed, so a
+// target that climbs out through another link is caught; components that
+// do not exist are taken literally.
+func linkInside(root, rel, target string) bool {
+	var cur []string
+	if dir := filepath.Dir(rel); dir != "." {
+		cur = strings.Split(filepath.ToSlash(dir), "/")
+	}
+	hops := 0
+	_, ok := walkLink(root, cur, target, &hops)
+	return ok
+}
+
+// walkLink resolves target from the directory cur (components below root)
+// and returns where it leads; ok is false once it leaves root.
+func walkLink(root string, cur []string, target string, hops *int) (_ []string, ok bool) {
+	if !relativeTarget(target) {
+		return nil, false
+	}
+	for _, c := range strings.Split(filepath.ToSlash(target), "/") {
+		switch c {
+		case "", ".":
+			continue
+		case "..":
+			if len(cur) == 0 {
+				return nil, false
+			}
+			cur = cur[:len(cur)-1]
+			continue
+		}
+		next := append(cur[:len(cur):len(cur)], c)
+		p := filepath.Join(root, filepath.FromSlash(strings.Join(next, "/")))
+		st, err := os.Lstat(p)
+		if err != nil || st.Mode()&fs.ModeSymlink == 0 {
+			cur = next
+			continue
+		}
+		if *hops++; *hops > maxLinkHops {
+			return nil, false
+		}
+		t, err := os.Readlink(p)
+		if err != nil {
+			return nil, false
+		}
+		if cur, ok = walkLink(root, cur, t, hops); !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func extractFile(zf *zip.File, dest string, perm fs.FileMode) (int64, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, rc)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// set after writing: the umask does not apply and read-only files
+		// can still be written
+		err = os.Chmod(dest, perm)
+	}
+	return n, err
+}
+
+// permOr returns the permission bits of mode, or def when the archive did
+// not record any.
+func permOr(mode fs.FileMode, def fs.FileMode) fs.FileMode {
+	if perm := mode.Perm(); perm != 0 {
+		return perm
+	}
+	return def
+}
@@ -2,13 +2,41 @@ package deleter
 
 import (
 	"context"
+	"errors"
 	"os"
 	"sync"
 )
 
+// ErrProtected is the failure reported for a protected target when
+// Options.Force is not set.
+var ErrProtected = errors.New("protected: tracked in git (force to delete)")
+
 type Target struct {
-	Path string
-	Size int64
+	Path  string
+	Size  int64
+	Files int64 // entries in the tree when known; drives the ETA
+
+	// Protected targets, such as a node_modules vendored into git, are
+	// refused unless Options.Force is set.
+	Protected bool
+}
+
+// Strategy takes one target out of the tree.
+type Strategy interface {
+	Remove(t Target) error
+}
+
+// Permanent removes targets for good. It is the default Strategy.
+type Permanent struct{}
+
+func (Permanent) Remove(t Target) error { return os.RemoveAll(t.Path) }
+
+// Options controls DeleteTargets.
+type Options struct {
+	Concurrency int
+	DryRun      bool     // report what would be deleted without removing anything
+	Force       bool     // delete protected targets too
+	Strategy    Strategy // how targets are removed; nil for Permanent
 }
 
 type Progress struct {
@@ -16,6 +44,11 @@ type Progress struct {
 	Total     int
 	Path      string
 	Err       error
+
+	// FilesDone and FilesTotal sum Target.Files over finished and all
+	// targets; both are zero when the counts are unknown.
+	FilesDone  int64
+	FilesTotal int64
 }
 
 type Failure struct {
@@ -27,20 +60,37 @@ type Summary struct {
 	Successes []Target
 	Failures  []Failure
 	Freed     int64
+
+	// Trashed counts the bytes moved to the trash rather than freed; they
+	// take up disk space until the trash is emptied.
+	Trashed int64
+
+	// Quarantined counts the bytes moved into quarantine; they are freed
+	// by a later Quarantine.Purge.
+	Quarantined int64
 }
 
 // DeleteTargets deletes all targets concurrently. It sends a Progress update
 // for each finished target on the provided progress channel. It returns a
 // final Summary when all work is done. The progress channel is not closed here
 // (caller may close it after consuming the returned summary if needed).
-func DeleteTargets(ctx context.Context, targets []Target, concurrency int, progress chan<- Progress, dryRun bool) Summary {
+func DeleteTargets(ctx context.Context, targets []Target, opts Options, progress chan<- Progress) Summary {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	concurrency := opts.Concurrency
 	if concurrency < 1 {
 		concurrency = 1
 	}
+	strategy := opts.Strategy
+	if strategy == nil {
+		strategy = Permanent{}
+	}
 	total := len(targets)
+	var filesTotal, filesDone int64
+	for _, t := range targets {
+		filesTotal += t.Files
+	}
 	type job struct{ t Target }
 	jobs := make(chan job)
 	var wg sync.WaitGroup
@@ -57,11 +107,13 @@ func DeleteTargets(ctx context.Context, targets []Target, concurrency int, progr
 			case <-ctx.Done():
 				err = ctx.Err()
 			default:
-				if dryRun {
+				if j.t.Protected && !opts.Force {
+					err = ErrProtected
+				} else if opts.DryRun {
 					// simulate success without deleting
 					err = nil
 				} else {
-					err = os.RemoveAll(j.t.Path)
+					err = strategy.Remove(j.t)
 				}
 			}
 			mu.Lock()
@@ -69,13 +121,21 @@ func DeleteTargets(ctx context.Context, targets []Target, concurrency int, progr
 				sum.Failures = append(sum.Failures, Failure{Path: j.t.Path, Err: err})
 			} else {
 				sum.Successes = append(sum.Successes, j.t)
-				sum.Freed += j.t.Size
+				switch strategy.(type) {
+				case Trash:
+					sum.Trashed += j.t.Size
+				case Quarantine:
+					sum.Quarantined += j.t.Size
+				default:
+					sum.Freed += j.t.Size
+				}
 			}
 			completed++
+			filesDone += j.t.Files
 			if progress != nil {
 				// Non-blocking best-effort send; avoid deadlock if receiver slow
 				select {
-				case progress <- Progress{Completed: completed, Total: total, Path: j.t.Path, Err: err}:
+				case progress <- Progress{Completed: completed, Total: total, Path: j.t.Path, Err: err, FilesDone: filesDone, FilesTotal: filesTotal}:
 				default:
 				}
 			}
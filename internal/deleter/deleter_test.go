@@ -1,9 +1,12 @@
 package deleter
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDeleteTargets_DryRunDoesNotDelete(t *testing.T) {
@@ -13,7 +16,7 @@ func TestDeleteTargets_DryRunDoesNotDelete(t *testing.T) {
 		t.Fatalf("mkdir: %v", err)
 	}
 	tgs := []Target{{Path: dir, Size: 1234}}
-	sum := DeleteTargets(nil, tgs, 1, nil, true)
+	sum := DeleteTargets(nil, tgs, Options{Concurrency: 1, DryRun: true}, nil)
 	if len(sum.Failures) != 0 {
 		t.Fatalf("unexpected failures: %v", sum.Failures)
 	}
@@ -24,3 +27,168 @@ func TestDeleteTargets_DryRunDoesNotDelete(t *testing.T) {
 		t.Fatalf("freed mismatch: %d", sum.Freed)
 	}
 }
+
+func TestDeleteTargets_ProgressCountsFiles(t *testing.T) {
+	root := t.TempDir()
+	var tgs []Target
+	for i, n := range []int64{3, 5} {
+		dir := filepath.Join(root, "p"+string(rune('a'+i)), "node_modules")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		tgs = append(tgs, Target{Path: dir, Files: n})
+	}
+	pch := make(chan Progress, len(tgs))
+	DeleteTargets(nil, tgs, Options{Concurrency: 1, DryRun: true}, pch)
+	close(pch)
+	var last Progress
+	for p := range pch {
+		last = p
+	}
+	if last.FilesTotal != 8 || last.FilesDone != 8 {
+		t.Fatalf("files progress = %d/%d, want 8/8", last.FilesDone, last.FilesTotal)
+	}
+}
+
+func TestDeleteTargets_RefusesProtected(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "node_modules")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	tgs := []Target{{Path: dir, Size: 10, Protected: true}}
+	sum := DeleteTargets(nil, tgs, Options{}, nil)
+	if len(sum.Failures) != 1 || !errors.Is(sum.Failures[0].Err, ErrProtected) || sum.Freed != 0 {
+		t.Fatalf("expected a protected failure, got %+v", sum)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("protected dir was removed: %v", err)
+	}
+
+	sum = DeleteTargets(nil, tgs, Options{Force: true}, nil)
+	if len(sum.Failures) != 0 || sum.Freed != 10 {
+		t.Fatalf("forced delete failed: %+v", sum)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("forced delete left the dir behind: %v", err)
+	}
+}
+
+func TestDeleteTargets_Trash(t *testing.T) {
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	root := t.TempDir()
+	var tgs []Target
+	for _, p := range []string{"a", "b"} {
+		dir := filepath.Join(root, p, "node_modules")
+		if err := os.MkdirAll(filepath.Join(dir, "x"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		tgs = append(tgs, Target{Path: dir, Size: 100})
+	}
+	sum := DeleteTargets(nil, tgs, Options{Concurrency: 1, Strategy: Trash{}}, nil)
+	if len(sum.Failures) != 0 || sum.Trashed != 200 || sum.Freed != 0 {
+		t.Fatalf("expected 200 bytes trashed, got %+v", sum)
+	}
+	trash := filepath.Join(data, "Trash")
+	for _, name := range []string{"node_modules", "node_modules.2"} {
+		if _, err := os.Stat(filepath.Join(trash, "files", name, "x")); err != nil {
+			t.Errorf("%s not in the trash: %v", name, err)
+		}
+		info, err := os.ReadFile(filepath.Join(trash, "info", name+".trashinfo"))
+		if err != nil {
+			t.Fatalf("info: %v", err)
+		}
+		if !strings.HasPrefix(string(info), "[Trash Info]\nPath="+root+"/") || !strings.Contains(string(info), "\nDeletionDate=") {
+			t.Errorf("unexpected trashinfo:\n%s", info)
+		}
+	}
+	for _, tg := range tgs {
+		if _, err := os.Stat(tg.Path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists: %v", tg.Path, err)
+		}
+	}
+}
+
+func TestTopdirTrash(t *testing.T) {
+	top := t.TempDir()
+	dir, err := topdirTrash(top, 1000)
+	if err != nil || dir != filepath.Join(top, ".Trash-1000") {
+		t.Fatalf("without .Trash: got %q (%v)", dir, err)
+	}
+	// a shared .Trash only counts when it is sticky
+	if err := os.Mkdir(filepath.Join(top, ".Trash"), 0o777); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if dir, _ := topdirTrash(top, 1000); dir != filepath.Join(top, ".Trash-1000") {
+		t.Fatalf("non-sticky .Trash used: %q", dir)
+	}
+	if err := os.Chmod(filepath.Join(top, ".Trash"), 0o777|os.ModeSticky); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	if dir, err := topdirTrash(top, 1000); err != nil || dir != filepath.Join(top, ".Trash", "1000") {
+		t.Fatalf("sticky .Trash: got %q (%v)", dir, err)
+	}
+}
+
+func TestQuarantine_RestoreAndPurge(t *testing.T) {
+	q := Quarantine{Dir: t.TempDir()}
+	root := t.TempDir()
+	var tgs []Target
+	for _, p := range []string{"a", "b"} {
+		dir := filepath.Join(root, p, "node_modules")
+		if err := os.MkdirAll(filepath.Join(dir, "x"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		tgs = append(tgs, Target{Path: dir, Size: 100, Files: 2})
+	}
+	sum := DeleteTargets(nil, tgs, Options{Concurrency: 2, Strategy: q}, nil)
+	if len(sum.Failures) != 0 || sum.Quarantined != 200 || sum.Freed != 0 {
+		t.Fatalf("expected 200 bytes quarantined, got %+v", sum)
+	}
+	entries, err := q.List()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v (%v)", entries, err)
+	}
+	for _, e := range entries {
+		if _, err := os.Stat(e.Path); !os.IsNotExist(err) {
+			t.Errorf("%s still in place: %v", e.Path, err)
+		}
+		if _, err := os.Stat(filepath.Join(e.Stored, "x")); err != nil || e.Size != 100 || e.Files != 2 {
+			t.Errorf("bad entry %+v (%v)", e, err)
+		}
+	}
+
+	restored, err := q.Restore(entries[0].ID)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(restored.Path, "x")); err != nil {
+		t.Fatalf("not restored: %v", err)
+	}
+	if _, err := q.Restore(entries[0].ID); !errors.Is(err, ErrNotQuarantined) {
+		t.Fatalf("expected ErrNotQuarantined, got %v", err)
+	}
+	// something new took the other entry's place
+	if err := os.MkdirAll(entries[1].Path, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, err := q.Restore(entries[1].ID); !errors.Is(err, ErrRestoreExists) {
+		t.Fatalf("expected ErrRestoreExists, got %v", err)
+	}
+
+	purged, failures, err := q.Purge(time.Now().Add(-time.Hour), false)
+	if err != nil || len(purged) != 0 || len(failures) != 0 {
+		t.Fatalf("nothing is an hour old yet, purged %+v %v (%v)", purged, failures, err)
+	}
+	purged, failures, err = q.Purge(time.Now().Add(time.Second), false)
+	if err != nil || len(purged) != 1 || len(failures) != 0 {
+		t.Fatalf("expected 1 purged, got %+v %v (%v)", purged, failures, err)
+	}
+	if _, err := os.Stat(purged[0].Stored); !os.IsNotExist(err) {
+		t.Fatalf("purged data left behind: %v", err)
+	}
+	if entries, _ := q.List(); len(entries) != 0 {
+		t.Fatalf("manifest not emptied: %+v", entries)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package deleter
+
+import "os"
+
+// lockFile is unavailable on this platform; manifestMu still serialises
+// manifest updates within the process.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
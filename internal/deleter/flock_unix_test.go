@@ -0,0 +1,31 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package deleter
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestLockManifest_HoldsAdvisoryLock(t *testing.T) {
+	dir := t.TempDir()
+	unlock, err := lockManifest(dir)
+	if err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	// another open file description stands in for another process
+	f, err := os.Open(filepath.Join(dir, manifestLockFile))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != syscall.EWOULDBLOCK {
+		t.Fatalf("flock while the manifest is locked: %v, want EWOULDBLOCK", err)
+	}
+	unlock()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Fatalf("flock after unlock: %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+package deleter
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// manifestFile lists the quarantined targets, one JSON entry per line.
+const manifestFile = "manifest.jsonl"
+
+// manifestLockFile carries the advisory lock on the manifest. It is a file
+// of its own because writeManifest replaces the manifest by renaming.
+const manifestLockFile = "manifest.lock"
+
+// manifestMu serialises manifest updates within the process; DeleteTargets
+// quarantines from several workers at once.
+var manifestMu sync.Mutex
+
+// ErrNotQuarantined is returned by Restore for an unknown id.
+var ErrNotQuarantined = errors.New("no such quarantine entry")
+
+// ErrRestoreExists is returned by Restore when something already sits at
+// the entry's original path.
+var ErrRestoreExists = errors.New("original path exists; move it away first")
+
+// Quarantine renames targets into a quarantine directory on their own
+// filesystem, which makes deleting a huge tree instant and undoable until
+// Purge removes it for real. Targets on the filesystem of Dir go below Dir;
+// others go to $topdir/.nmm-quarantine-$uid at the top of their mount. The
+// manifest in Dir records every entry with its original path.
+type Quarantine struct {
+	// Dir holds the manifest; "" for $XDG_DATA_HOME/node-module-man/quarantine,
+	// by default ~/.local/share/node-module-man/quarantine.
+	Dir string
+}
+
+// QuarantineEntry is one quarantined target.
+type QuarantineEntry struct {
+	ID     string    `json:"id"`
+	Path   string    `json:"path"`   // where the target was
+	Stored string    `json:"stored"` // where it is kept until restored or purged
+	Size   int64     `json:"size"`
+	Files  int64     `json:"files"`
+	Time   time.Time `json:"time"` // when it was quarantined
+}
+
+// DefaultDataDir returns the per-user data directory of node-module-man
+// ($XDG_DATA_HOME/node-module-man, by default ~/.local/share/node-module-man).
+func DefaultDataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "node-module-man"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "node-module-man"), nil
+}
+
+// dir resolves Quarantine.Dir.
+func (q Quarantine) dir() (string, error) {
+	if q.Dir != "" {
+		return q.Dir, nil
+	}
+	data, err := DefaultDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(data, "quarantine"), nil
+}
+
+func (q Quarantine) Remove(t Target) error {
+	path, err := filepath.Abs(t.Path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+	dir, err := q.dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	store := dir
+	if dev, ok := deviceOf(path); ok {
+		if d, ok := deviceOf(dir); ok && d != dev {
+			store = filepath.Join(mountTop(path, dev), ".nmm-quarantine-"+strconv.Itoa(os.Getuid()))
+			if err := os.MkdirAll(store, 0o700); err != nil {
+				return err
+			}
+		}
+	}
+	id, err := newQuarantineID()
+	if err != nil {
+		return err
+	}
+	holder := filepath.Join(store, id)
+	if err := os.Mkdir(holder, 0o700); err != nil {
+		return err
+	}
+	e := QuarantineEntry{ID: id, Path: path, Stored: filepath.Join(holder, filepath.Base(path)), Size: t.Size, Files: t.Files, Time: time.Now()}
+	if err := os.Rename(path, e.Stored); err != nil {
+		_ = os.Remove(holder)
+		return err
+	}
+	if err := q.appendEntry(dir, e); err != nil {
+		// keep the target where it was rather than lose track of it
+		if rerr := os.Rename(e.Stored, path); rerr == nil {
+			_ = os.Remove(holder)
+		}
+		return err
+	}
+	return nil
+}
+
+// newQuarantineID returns a sortable, unique entry id such as
+// "20240502-101500-9f3a1c".
+func newQuarantineID() (string, error) {
+	var b [3]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b[:]), nil
+}
+
+func (q Quarantine) appendEntry(dir string, e QuarantineEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	unlock, err := lockManifest(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := os.OpenFile(filepath.Join(dir, manifestFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// List returns the quarantined entries, oldest first.
+func (q Quarantine) List() ([]QuarantineEntry, error) {
+	dir, err := q.dir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return []QuarantineEntry{}, nil
+	}
+	unlock, err := lockManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return readManifest(dir)
+}
+
+// lockManifest serialises access to the manifest in dir: manifestMu within
+// the process and an advisory lock across processes, so that a purge or
+// restore rewriting the manifest cannot drop an entry another run appends
+// meanwhile. unlock releases both.
+func lockManifest(dir string) (unlock func(), err error) {
+	manifestMu.Lock()
+	defer func() {
+		if err != nil {
+			manifestMu.Unlock()
+		}
+	}()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, manifestLockFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+		manifestMu.Unlock()
+	}, nil
+}
+
+func readManifest(dir string) ([]QuarantineEntry, error) {
+	f, err := os.Open(filepath.Join(dir, manifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []QuarantineEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := []QuarantineEntry{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e QuarantineEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil && e.ID != "" {
+			entries = append(entries, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+// writeManifest replaces the manifest with entries.
+func writeManifest(dir string, entries []QuarantineEntry) error {
+	tmp, err := os.CreateTemp(dir, manifestFile+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err = enc.Encode(e); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(dir, manifestFile))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Restore moves the entry with id back to its original path, recreating
+// missing parent directories, and drops it from the manifest.
+func (q Quarantine) Restore(id string) (QuarantineEntry, error) {
+	dir, err := q.dir()
+	if err != nil {
+		return QuarantineEntry{}, err
+	}
+	unlock, err := lockManifest(dir)
+	if err != nil {
+		return QuarantineEntry{}, err
+	}
+	defer unlock()
+	entries, err := readManifest(dir)
+	if err != nil {
+		return QuarantineEntry{}, err
+	}
+	for i, e := range entries {
+		if e.ID != id {
+			continue
+		}
+		if _, err := os.Lstat(e.Path); err == nil {
+			return e, fmt.Errorf("%s: %w", e.Path, ErrRestoreExists)
+		}
+		if err := os.MkdirAll(filepath.Dir(e.Path), 0o755); err != nil {
+			return e, err
+		}
+		if err := os.Rename(e.Stored, e.Path); err != nil {
+			return e, err
+		}
+		_ = os.Remove(filepath.Dir(e.Stored))
+		return e, writeManifest(dir, append(entries[:i:i], entries[i+1:]...))
+	}
+	return QuarantineEntry{}, fmt.Errorf("%s: %w", id, ErrNotQuarantined)
+}
+
+// Purge removes for good the entries quarantined before cutoff and drops
+// them from the manifest. Failures carry the entries' original paths.
+func (q Quarantine) Purge(cutoff time.Time, dryRun bool) ([]QuarantineEntry, []Failure, error) {
+	dir, err := q.dir()
+	if err != nil {
+		return nil, nil, err
+	}
+	unlock, err := lockManifest(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer unlock()
+	entries, err := readManifest(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var purged []QuarantineEntry
+	var failures []Failure
+	kept := entries[:0:0]
+	for _, e := range entries {
+		if !e.Time.Before(cutoff) {
+			kept = append(kept, e)
+			continue
+		}
+		if !dryRun {
+			if err := os.RemoveAll(filepath.Dir(e.Stored)); err != nil {
+				failures = append(failures, Failure{Path: e.Path, Err: err})
+				kept = append(kept, e)
+				continue
+			}
+		}
+		purged = append(purged, e)
+	}
+	if dryRun || len(purged) == 0 {
+		return purged, failures, nil
+	}
+	return purged, failures, writeManifest(dir, kept)
+}
@@ -0,0 +1,177 @@
+package deleter
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Trash moves targets into the freedesktop.org trash, where file managers
+// can restore them from. A target on the filesystem of the home trash goes
+// there; one on another filesystem goes to the trash at the top of its
+// mount: $topdir/.Trash/$uid when an administrator set up $topdir/.Trash,
+// else $topdir/.Trash-$uid. Targets are renamed, never copied.
+type Trash struct {
+	// Home is the home trash; "" for $XDG_DATA_HOME/Trash, by default
+	// ~/.local/share/Trash.
+	Home string
+}
+
+// ErrNoTrash is returned for a target that no trash on its filesystem can
+// take.
+var ErrNoTrash = errors.New("no trash directory on the target's filesystem")
+
+// homeTrash resolves Trash.Home.
+func (t Trash) homeTrash() (string, error) {
+	if t.Home != "" {
+		return t.Home, nil
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "Trash"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+func (t Trash) Remove(target Target) error {
+	path, err := filepath.Abs(target.Path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+	dir, topdir, err := t.dirFor(path)
+	if err != nil {
+		return err
+	}
+	// the .trashinfo Path of a per-mount trash is relative to its mount
+	infoPath := path
+	if topdir != "" {
+		if rel, err := filepath.Rel(topdir, path); err == nil {
+			infoPath = rel
+		}
+	}
+	return trashInto(dir, path, infoPath)
+}
+
+// dirFor picks the trash for path, creating it as needed. topdir is the
+// mount top of a per-mount trash and "" for the home trash.
+func (t Trash) dirFor(path string) (dir, topdir string, err error) {
+	home, err := t.homeTrash()
+	if err != nil {
+		return "", "", err
+	}
+	dev, ok := deviceOf(path)
+	if homeDev, homeOK := deviceOf(existingAncestor(home)); !ok || !homeOK || dev == homeDev {
+		return home, "", os.MkdirAll(home, 0o700)
+	}
+	topdir = mountTop(path, dev)
+	dir, err = topdirTrash(topdir, os.Getuid())
+	return dir, topdir, err
+}
+
+// topdirTrash returns the trash of the mount at topdir for user uid:
+// $topdir/.Trash/$uid when $topdir/.Trash is a sticky directory and not a
+// symlink, else $topdir/.Trash-$uid.
+func topdirTrash(topdir string, uid int) (string, error) {
+	id := strconv.Itoa(uid)
+	shared := filepath.Join(topdir, ".Trash")
+	if st, err := os.Lstat(shared); err == nil && st.IsDir() && st.Mode()&os.ModeSticky != 0 {
+		dir := filepath.Join(shared, id)
+		if err := os.MkdirAll(dir, 0o700); err == nil {
+			return dir, nil
+		}
+	}
+	dir := filepath.Join(topdir, ".Trash-"+id)
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, fs.ErrExist) {
+		return "", fmt.Errorf("%w: %v", ErrNoTrash, err)
+	}
+	if st, err := os.Lstat(dir); err != nil || !st.IsDir() {
+		return "", ErrNoTrash
+	}
+	return dir, nil
+}
+
+// trashInto moves path into the trash at dir. The .trashinfo file is
+// created first, exclusively, to claim a name that is free in files/.
+func trashInto(dir, path, infoPath string) error {
+	files, info := filepath.Join(dir, "files"), filepath.Join(dir, "info")
+	for _, d := range []string{files, info} {
+		if err := os.MkdirAll(d, 0o700); err != nil {
+			return err
+		}
+	}
+	content := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: infoPath}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+	base := filepath.Base(path)
+	for i := 1; i < 10000; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d", base, i)
+		}
+		infoFile := filepath.Join(info, name+".trashinfo")
+		f, err := os.OpenFile(infoFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = f.WriteString(content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			dest := filepath.Join(files, name)
+			if _, serr := os.Lstat(dest); serr == nil {
+				// a leftover without its info file: keep looking
+				_ = os.Remove(infoFile)
+				continue
+			}
+			err = os.Rename(path, dest)
+		}
+		if err != nil {
+			_ = os.Remove(infoFile)
+		}
+		return err
+	}
+	return fmt.Errorf("no free name for %s in %s", base, files)
+}
+
+// existingAncestor returns path or its nearest existing parent.
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+// mountTop walks up from path to the top directory of the mount with
+// device dev.
+func mountTop(path string, dev uint64) string {
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		if d, ok := deviceOf(parent); !ok || d != dev {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package deleter
+
+// deviceOf is unavailable on this platform; every target goes to the home
+// trash.
+func deviceOf(path string) (uint64, bool) {
+	return 0, false
+}
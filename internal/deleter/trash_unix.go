@@ -0,0 +1,22 @@
+//go:build unix
+
+package deleter
+
+import (
+	"os"
+	"syscall"
+)
+
+// deviceOf returns the device holding path, without following a final
+// symlink.
+func deviceOf(path string) (uint64, bool) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
@@ -0,0 +1,259 @@
+// Package journal keeps a persistent record of what node-module-man did to
+// the trees it deleted, compressed and restored, so an archived node_modules
+// can be found again long after the run that wrote it.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"node-module-man/internal/compressor"
+	"node-module-man/internal/deleter"
+)
+
+// journalFile is the journal's name in the data directory.
+const journalFile = "journal.jsonl"
+
+// mu serialises appends within the process.
+var mu sync.Mutex
+
+// ErrNoArchive is returned by Archives when the journal records no archive
+// for a path.
+var ErrNoArchive = errors.New("no archive recorded for this path")
+
+// Op is the kind of a journaled operation.
+type Op string
+
+const (
+	OpDelete     Op = "delete"     // removed for good
+	OpTrash      Op = "trash"      // moved to the trash
+	OpQuarantine Op = "quarantine" // moved into quarantine
+	OpCompress   Op = "compress"   // archived, and removed with Removed set
+	OpRestore    Op = "restore"    // extracted back from Archive
+)
+
+// Entry is one journaled operation on one directory.
+type Entry struct {
+	Time  time.Time `json:"time"`
+	Op    Op        `json:"op"`
+	Path  string    `json:"path"` // the directory acted on
+	Size  int64     `json:"size"`
+	Files int64     `json:"files,omitempty"`
+
+	// Archive, ArchiveSize and Checksum (sha256, hex) describe the zip of
+	// a compress or restore.
+	Archive     string `json:"archive,omitempty"`
+	ArchiveSize int64  `json:"archiveSize,omitempty"`
+	Checksum    string `json:"checksum,omitempty"`
+	Removed     bool   `json:"removed,omitempty"` // compress: the source was deleted afterwards
+}
+
+// Journal is an append-only JSONL file of Entries.
+type Journal struct {
+	// Path is the journal file; "" for journal.jsonl in the data directory
+	// ($XDG_DATA_HOME/node-module-man, by default ~/.local/share/node-module-man).
+	Path string
+}
+
+// file resolves Journal.Path.
+func (j Journal) file() (string, error) {
+	if j.Path != "" {
+		return j.Path, nil
+	}
+	dir, err := deleter.DefaultDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, journalFile), nil
+}
+
+// Record appends entries to the journal, stamping those without a Time.
+func (j Journal) Record(entries ...Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	path, err := j.file()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var buf []byte
+	for _, e := range entries {
+		if e.Time.IsZero() {
+			e.Time = now
+		}
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Entries returns the journaled operations, oldest first. Lines that do
+// not parse are skipped.
+func (j Journal) Entries() ([]Entry, error) {
+	path, err := j.file()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := []Entry{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) == nil && e.Op != "" {
+			entries = append(entries, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, k int) bool { return entries[i].Time.Before(entries[k].Time) })
+	return entries, nil
+}
+
+// Archives returns the newest archive recorded for path, or, when path is
+// a project directory, for each archived directory in it. Archives that no
+// longer exist are passed over for older ones.
+func (j Journal) Archives(path string) ([]Entry, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var out []Entry
+	found := map[string]bool{}
+	var missing error
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Op != OpCompress || found[e.Path] || (e.Path != path && filepath.Dir(e.Path) != path) {
+			continue
+		}
+		if _, err := os.Stat(e.Archive); err != nil {
+			if missing == nil {
+				missing = err
+			}
+			continue
+		}
+		found[e.Path] = true
+		out = append(out, e)
+	}
+	if len(out) == 0 {
+		if missing != nil {
+			return nil, missing
+		}
+		return nil, fmt.Errorf("%s: %w", path, ErrNoArchive)
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].Path < out[k].Path })
+	return out, nil
+}
+
+// ArchiveTarget returns the compress entry e as a target restoring its
+// directory in place.
+func (e Entry) ArchiveTarget() compressor.ArchiveTarget {
+	return compressor.ArchiveTarget{Path: e.Archive, Dir: filepath.Dir(e.Path), Checksum: e.Checksum}
+}
+
+// ArchiveTargets turns archive paths into restore targets. Archives the
+// journal records are restored where they came from and checked against
+// their checksums; others are restored next to the archive.
+func (j Journal) ArchiveTargets(archives []string) ([]compressor.ArchiveTarget, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return nil, err
+	}
+	newest := map[string]Entry{}
+	for _, e := range entries {
+		if e.Op == OpCompress {
+			newest[e.Archive] = e
+		}
+	}
+	out := make([]compressor.ArchiveTarget, 0, len(archives))
+	for _, a := range archives {
+		if e, ok := newest[absPath(a)]; ok {
+			out = append(out, e.ArchiveTarget())
+			continue
+		}
+		out = append(out, compressor.ArchiveTarget{Path: a})
+	}
+	return out, nil
+}
+
+// FromDelete turns the successes of a deleter run made with strategy op
+// into entries.
+func FromDelete(sum deleter.Summary, op Op) []Entry {
+	out := make([]Entry, 0, len(sum.Successes))
+	for _, t := range sum.Successes {
+		out = append(out, Entry{Op: op, Path: absPath(t.Path), Size: t.Size, Files: t.Files})
+	}
+	return out
+}
+
+// FromPurge turns the quarantine entries a purge removed for good into
+// delete entries of the paths they were quarantined from.
+func FromPurge(purged []deleter.QuarantineEntry) []Entry {
+	out := make([]Entry, 0, len(purged))
+	for _, q := range purged {
+		out = append(out, Entry{Op: OpDelete, Path: q.Path, Size: q.Size, Files: q.Files})
+	}
+	return out
+}
+
+// FromCompress turns the successes of a compressor run into entries.
+func FromCompress(sum compressor.Summary) []Entry {
+	out := make([]Entry, 0, len(sum.Successes))
+	for _, s := range sum.Successes {
+		out = append(out, Entry{Op: OpCompress, Path: absPath(s.Path), Size: s.SourceSize, Files: s.SourceFiles,
+			Archive: absPath(s.Dest), ArchiveSize: s.Size, Checksum: s.Checksum, Removed: s.Removed})
+	}
+	return out
+}
+
+// FromDecompress turns the archives a compressor run restored into entries.
+func FromDecompress(sum compressor.RestoreSummary) []Entry {
+	out := make([]Entry, 0, len(sum.Restored))
+	for _, r := range sum.Restored {
+		out = append(out, Entry{Op: OpRestore, Path: absPath(r.Path), Size: r.Size, Files: r.Files, Archive: absPath(r.Archive)})
+	}
+	return out
+}
+
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
@@ -0,0 +1,88 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"node-module-man/internal/compressor"
+	"node-module-man/internal/deleter"
+)
+
+func TestJournal_CompressThenRestore(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "app")
+	nm := filepath.Join(project, "node_modules")
+	if err := os.MkdirAll(filepath.Join(nm, "left-pad"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(nm, "left-pad", "index.js"), []byte("module.exports = 1\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	j := Journal{Path: filepath.Join(root, "data", "journal.jsonl")}
+
+	sum := compressor.CompressTargets(context.Background(), []compressor.Target{{Path: nm, Size: 19, Files: 1}}, compressor.Options{DeleteAfter: true}, nil)
+	if len(sum.Failures) != 0 {
+		t.Fatalf("compress failures: %v", sum.Failures)
+	}
+	if err := j.Record(FromCompress(sum)...); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if err := j.Record(FromDelete(deleter.Summary{Successes: []deleter.Target{{Path: filepath.Join(root, "other", "node_modules"), Size: 7}}}, OpTrash)...); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	entries, err := j.Entries()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("entries = %v, %v; want 2", entries, err)
+	}
+	if e := entries[0]; e.Op != OpCompress || e.Path != nm || e.Size != 19 || !e.Removed || e.Time.IsZero() {
+		t.Fatalf("compress entry = %+v", e)
+	}
+	if entries[1].Op != OpTrash || entries[1].Size != 7 {
+		t.Fatalf("trash entry = %+v", entries[1])
+	}
+
+	// the project directory finds its node_modules' archive
+	found, err := j.Archives(project)
+	if err != nil || len(found) != 1 || found[0].Path != nm {
+		t.Fatalf("archives = %v, %v", found, err)
+	}
+	if sum, err := compressor.Checksum(found[0].Archive); err != nil || sum != found[0].Checksum {
+		t.Fatalf("checksum = %q, %v; journal has %q", sum, err, found[0].Checksum)
+	}
+	rs := compressor.DecompressTargets(context.Background(), []compressor.ArchiveTarget{{Path: found[0].Archive, Dir: filepath.Dir(found[0].Path), Checksum: found[0].Checksum}}, compressor.DecompressOptions{NoOverwrite: true}, nil)
+	if len(rs.Failures) != 0 || len(rs.Restored) != 1 || rs.Restored[0].Path != nm {
+		t.Fatalf("decompress = %+v", rs)
+	}
+	if b, err := os.ReadFile(filepath.Join(nm, "left-pad", "index.js")); err != nil || string(b) != "module.exports = 1\n" {
+		t.Fatalf("restored file = %q, %v", b, err)
+	}
+
+	if _, err := j.Archives(filepath.Join(root, "other")); !errors.Is(err, ErrNoArchive) {
+		t.Fatalf("archives of a trashed path: err = %v, want ErrNoArchive", err)
+	}
+	if err := os.Remove(found[0].Archive); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, err := j.Archives(nm); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("archives after the zip is gone: err = %v, want not-exist", err)
+	}
+}
+
+func TestJournal_RecordsPurges(t *testing.T) {
+	j := Journal{Path: filepath.Join(t.TempDir(), "journal.jsonl")}
+	purged := []deleter.QuarantineEntry{{ID: "1", Path: "/p/app/node_modules", Stored: "/q/1", Size: 42, Files: 3}}
+	if err := j.Record(FromPurge(purged)...); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	entries, err := j.Entries()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("entries = %v, %v; want 1", entries, err)
+	}
+	if e := entries[0]; e.Op != OpDelete || e.Path != "/p/app/node_modules" || e.Size != 42 || e.Files != 3 {
+		t.Fatalf("purge entry = %+v", e)
+	}
+}
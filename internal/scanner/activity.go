@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// activityScanLimit caps how many entries lastActivity inspects per project
+// so that a scan rooted at a huge monorepo stays cheap.
+const activityScanLimit = 20000
+
+var errActivityLimit = errors.New("activity scan limit reached")
+
+// skipActivityDirs are never descended into when looking for project activity.
+var skipActivityDirs = map[string]struct{}{
+	"node_modules": {},
+	".git":         {},
+	".hg":          {},
+	".svn":         {},
+}
+
+// lastActivity returns the newest modification time among the project's own
+// files, ignoring node_modules and VCS metadata. When the walk yields nothing,
+// the lockfile and package.json mtimes are used as cheap fallbacks.
+func lastActivity(ctx context.Context, p ProjectInfo) time.Time {
+	var newest time.Time
+	seen := 0
+	_ = filepath.WalkDir(p.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if d.IsDir() {
+			if _, skip := skipActivityDirs[d.Name()]; skip && path != p.Dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		seen++
+		if seen > activityScanLimit {
+			return errActivityLimit
+		}
+		if info, e := d.Info(); e == nil && info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	if !newest.IsZero() {
+		return newest
+	}
+	for _, f := range []string{p.LockfilePath, filepath.Join(p.Dir, "package.json")} {
+		if f == "" {
+			continue
+		}
+		if st, err := os.Stat(f); err == nil && st.ModTime().After(newest) {
+			newest = st.ModTime()
+		}
+	}
+	return newest
+}
+
+// filtersByAge reports whether OlderThan or NewerThan is set.
+func (o Options) filtersByAge() bool {
+	return o.OlderThan > 0 || o.NewerThan > 0
+}
+
+// keepByAge applies the OlderThan/NewerThan filters to a result. Results with
+// unknown activity never pass an age filter.
+func (o Options) keepByAge(r ResultItem, now time.Time) bool {
+	if !o.filtersByAge() {
+		return true
+	}
+	if r.LastActivity.IsZero() {
+		return false
+	}
+	age := now.Sub(r.LastActivity)
+	if o.OlderThan > 0 && age < o.OlderThan {
+		return false
+	}
+	if o.NewerThan > 0 && age > o.NewerThan {
+		return false
+	}
+	return true
+}
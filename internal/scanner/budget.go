@@ -0,0 +1,38 @@
+package scanner
+
+import (
+	"errors"
+	"time"
+)
+
+// errBudget stops a size walk whose budget ran out; it is never reported.
+var errBudget = errors.New("size budget exhausted")
+
+// budget bounds a single dirSize computation; zero fields are unlimited.
+type budget struct {
+	entries  int64
+	bytes    int64
+	deadline time.Time
+}
+
+// budget returns the per-directory limits from the options. The timeout
+// starts counting now.
+func (o Options) budget() budget {
+	b := budget{entries: o.MaxEntries, bytes: o.MaxBytes}
+	if o.DirTimeout > 0 {
+		b.deadline = time.Now().Add(o.DirTimeout)
+	}
+	return b
+}
+
+// exhausted reports whether a walk that visited entries and measured u has
+// used up the budget.
+func (b budget) exhausted(entries int64, u usage) bool {
+	if b.entries > 0 && entries >= b.entries {
+		return true
+	}
+	if b.bytes > 0 && u.apparent >= b.bytes {
+		return true
+	}
+	return !b.deadline.IsZero() && time.Now().After(b.deadline)
+}
@@ -0,0 +1,202 @@
+package scanner
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// indexVersion is bumped whenever the on-disk index format changes.
+const indexVersion = 3
+
+const indexFile = "size-index.json"
+
+// fingerprintMarkers are files inside node_modules that package managers
+// rewrite on every install.
+var fingerprintMarkers = []string{".package-lock.json", ".modules.yaml", ".yarn-state.yml"}
+
+// DefaultCacheDir returns the per-user cache directory for the size index
+// ($XDG_CACHE_HOME/node-module-man on Linux).
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "node-module-man"), nil
+}
+
+// cacheEntry is a remembered measurement of one directory. Only complete
+// measurements are stored, so the figures do not depend on the budgets.
+type cacheEntry struct {
+	Fingerprint   uint64        `json:"fingerprint"`
+	FollowSymlink bool          `json:"followSymlink,omitempty"`
+	Size          int64         `json:"size"`
+	Reclaimable   int64         `json:"reclaimable"`
+	Files         int64         `json:"files"`
+	Dirs          int64         `json:"dirs"`
+	Symlinks      int64         `json:"symlinks"`
+	Linked        []linkedInode `json:"linked,omitempty"`
+	Measured      time.Time     `json:"measured"`
+}
+
+// reclaimable claims the entry's multiply-linked inodes like a walk would
+// and returns the reclaimable bytes left to this result: an inode already
+// counted for another result of the scan is not counted again.
+func (e cacheEntry) reclaimable(claims *inodeClaims) int64 {
+	n := e.Reclaimable
+	for _, l := range e.Linked {
+		if !claims.claim(fileID{dev: l.Dev, ino: l.Ino}) {
+			n -= l.Allocated
+		}
+	}
+	return n
+}
+
+type indexFormat struct {
+	Version int                   `json:"version"`
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+// sizeIndex is the persistent size cache keyed by absolute directory path.
+type sizeIndex struct {
+	path    string
+	refresh bool
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// openSizeIndex loads the index from dir. A missing or unreadable index
+// starts empty; the cache is an optimisation and never fails a scan.
+func openSizeIndex(dir string, refresh bool) *sizeIndex {
+	ix := &sizeIndex{
+		path:    filepath.Join(dir, indexFile),
+		refresh: refresh,
+		entries: make(map[string]cacheEntry),
+	}
+	data, err := os.ReadFile(ix.path)
+	if err != nil {
+		return ix
+	}
+	var f indexFormat
+	if json.Unmarshal(data, &f) == nil && f.Version == indexVersion && f.Entries != nil {
+		ix.entries = f.Entries
+	}
+	return ix
+}
+
+// lookup returns the stored entry for path when its fingerprint matches and
+// it was measured with the same symlink handling.
+func (ix *sizeIndex) lookup(path string, fp uint64, followSymlink bool) (cacheEntry, bool) {
+	if ix.refresh {
+		return cacheEntry{}, false
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	e, ok := ix.entries[path]
+	if !ok || e.Fingerprint != fp || e.FollowSymlink != followSymlink {
+		return cacheEntry{}, false
+	}
+	return e, true
+}
+
+func (ix *sizeIndex) store(path string, fp uint64, followSymlink bool, u usage) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.entries[path] = cacheEntry{
+		Fingerprint:   fp,
+		FollowSymlink: followSymlink,
+		Size:          u.apparent,
+		Reclaimable:   u.reclaimable,
+		Files:         u.files,
+		Dirs:          u.dirs,
+		Symlinks:      u.symlinks,
+		Linked:        u.linked,
+		Measured:      time.Now(),
+	}
+	ix.dirty = true
+}
+
+// save writes the index atomically, dropping entries whose directory no
+// longer exists.
+func (ix *sizeIndex) save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for p := range ix.entries {
+		if _, err := os.Lstat(p); os.IsNotExist(err) {
+			delete(ix.entries, p)
+			ix.dirty = true
+		}
+	}
+	if !ix.dirty {
+		return nil
+	}
+	data, err := json.Marshal(indexFormat{Version: indexVersion, Entries: ix.entries})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0o755); err != nil {
+		return err
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	ix.dirty = false
+	return nil
+}
+
+// fingerprint hashes the mtimes of a node_modules directory, its install
+// marker files and its top-level package directories (including the packages
+// inside @scope directories). Any install, removal or upgrade of a top-level
+// package changes at least one of them. Build outputs such as dist or .next
+// can change below an untouched top level, so only node_modules is indexed.
+func fingerprint(dir string) uint64 {
+	h := fnv.New64a()
+	add := func(name string, info os.FileInfo) {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(strconv.FormatInt(info.ModTime().UnixNano(), 10)))
+		h.Write([]byte{0})
+	}
+	if st, err := os.Stat(dir); err == nil {
+		add(".", st)
+	}
+	for _, m := range fingerprintMarkers {
+		if st, err := os.Stat(filepath.Join(dir, m)); err == nil {
+			add(m, st)
+		}
+	}
+	entries, err := os.ReadDir(dir) // sorted by name
+	if err != nil {
+		return h.Sum64()
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		add(e.Name(), info)
+		if e.IsDir() && len(e.Name()) > 1 && e.Name()[0] == '@' {
+			scoped, err := os.ReadDir(filepath.Join(dir, e.Name()))
+			if err != nil {
+				continue
+			}
+			for _, se := range scoped {
+				if si, err := se.Info(); err == nil {
+					add(e.Name()+"/"+se.Name(), si)
+				}
+			}
+		}
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,172 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// CacheKind identifies a package manager cache or store.
+type CacheKind string
+
+const (
+	CacheNPM       CacheKind = "npm"        // ~/.npm/_cacache
+	CacheYarn      CacheKind = "yarn"       // yarn classic's global cache
+	CacheYarnBerry CacheKind = "yarn-berry" // yarn 2+'s global mirror or a project's .yarn/cache
+	CachePNPM      CacheKind = "pnpm-store" // pnpm's content-addressable store
+	CacheBun       CacheKind = "bun"        // ~/.bun/install/cache
+)
+
+// CacheKinds lists the cache kinds in display order.
+var CacheKinds = []CacheKind{CacheNPM, CacheYarn, CacheYarnBerry, CachePNPM, CacheBun}
+
+// Cache is a package manager cache found on disk.
+type Cache struct {
+	Kind    CacheKind
+	Path    string
+	Project string // project owning a .yarn/cache; empty for global caches
+	Size    int64  // apparent size
+
+	// Reclaimable leaves out files hardlinked from outside the cache, such
+	// as pnpm store files linked into node_modules.
+	Reclaimable int64
+	Files       int64
+	Partial     bool
+	Err         *ScanError
+}
+
+// CacheReport is the result of ScanCaches.
+type CacheReport struct {
+	Caches []Cache
+
+	// Projects are the projects found below the scan root. Pruning keeps
+	// whatever their lockfiles and installs reference.
+	Projects []ProjectInfo
+
+	// Tools are the cached browser and runtime downloads (see Tool), each
+	// marked used or unused by the projects.
+	Tools []ToolVersion
+
+	// Runtimes are the Node versions installed by nvm, fnm and volta, each
+	// marked used or unused by the projects' version requests.
+	Runtimes []NodeRuntime
+}
+
+// globalCaches lists the existing global caches of every package manager.
+func globalCaches() []Cache {
+	var out []Cache
+	seen := map[string]bool{}
+	add := func(kind CacheKind, dir string) {
+		if dir == "" || seen[dir] {
+			return
+		}
+		if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+			return
+		}
+		seen[dir] = true
+		out = append(out, Cache{Kind: kind, Path: dir})
+	}
+	add(CacheNPM, npmCacheDir())
+	for _, d := range yarnClassicCacheDirs() {
+		add(CacheYarn, d)
+	}
+	add(CacheYarnBerry, yarnBerryCacheDir())
+	for _, d := range pnpmStoreRoots() {
+		add(CachePNPM, d)
+	}
+	add(CacheBun, bunCacheDir())
+	return out
+}
+
+// ScanCaches finds the global package manager caches, the .yarn/cache
+// directories of the projects below root, the cached tool downloads and the
+// installed Node runtimes, and measures them like scan results. It also
+// returns the walk errors, nil when there are none.
+func ScanCaches(ctx context.Context, root string, opts Options) (CacheReport, []*ScanError) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	opts = opts.withDefaults()
+	// a project shows up through its node_modules or, with Plug'n'Play,
+	// through its .yarn directory
+	opts.Targets = []TargetSpec{DefaultTargets[0], {Kind: "yarn", Name: ".yarn", Requires: []string{"yarn.lock"}}}
+
+	var mu sync.Mutex
+	dirs := map[string]bool{}
+	var walkErrs []*ScanError
+	discover(ctx, root, opts, func(c candidate) {
+		mu.Lock()
+		dirs[filepath.Dir(c.path)] = true
+		mu.Unlock()
+	}, func(path string, err error) {
+		mu.Lock()
+		walkErrs = append(walkErrs, newScanError(OpWalk, path, err))
+		mu.Unlock()
+	})
+
+	var rep CacheReport
+	for dir := range dirs {
+		rep.Projects = append(rep.Projects, readProject(dir))
+	}
+	sort.Slice(rep.Projects, func(i, j int) bool { return rep.Projects[i].Dir < rep.Projects[j].Dir })
+
+	rep.Caches = globalCaches()
+	for _, p := range rep.Projects {
+		dir := filepath.Join(p.Dir, ".yarn", "cache")
+		if st, err := os.Stat(dir); err == nil && st.IsDir() {
+			rep.Caches = append(rep.Caches, Cache{Kind: CacheYarnBerry, Path: dir, Project: p.Dir})
+		}
+	}
+
+	claims := newInodeClaims()
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i := range rep.Caches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(c *Cache) {
+			defer func() { <-sem; wg.Done() }()
+			c.measure(ctx, claims, opts)
+		}(&rep.Caches[i])
+	}
+	rep.Tools = scanToolCaches(rep.Projects)
+	for i := range rep.Tools {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(v *ToolVersion) {
+			defer func() { <-sem; wg.Done() }()
+			v.measure(ctx, claims, opts)
+		}(&rep.Tools[i])
+	}
+	rep.Runtimes = scanNodeRuntimes(rep.Projects)
+	for i := range rep.Runtimes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(rt *NodeRuntime) {
+			defer func() { <-sem; wg.Done() }()
+			rt.measure(ctx, claims, opts)
+		}(&rep.Runtimes[i])
+	}
+	wg.Wait()
+	return rep, walkErrs
+}
+
+// MeasureCache measures c again, e.g. after pruning it.
+func MeasureCache(ctx context.Context, c Cache, opts Options) Cache {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	c.Err = nil
+	c.measure(ctx, nil, opts)
+	return c
+}
+
+func (c *Cache) measure(ctx context.Context, claims *inodeClaims, opts Options) {
+	u, err := dirSize(ctx, c.Path, false, claims, opts.budget())
+	c.Size, c.Reclaimable, c.Files, c.Partial = u.apparent, u.reclaimable, u.files, u.partial
+	if err != nil {
+		c.Err = newScanError(OpSize, c.Path, err)
+	}
+}
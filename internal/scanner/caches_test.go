@@ -0,0 +1,67 @@
+package scanner
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestScanCaches(t *testing.T) {
+	home := isolateCaches(t)
+	writeFiles(t, home, map[string]string{
+		".npm/_cacache/content-v2/sha512/aa/bb/cc":             "1234",
+		"yarn-cache/v6/npm-a-1.0.0-abc-integrity/package.json": "{}",
+		"pnpm-store/v3/files/aa/bbcc":                          "12",
+		"bun-cache/a@1.0.0@@@1/package.json":                   "{}",
+		".yarn/berry/cache/a-npm-1.0.0-abc-8c.zip":             "zip",
+		".local/share/pnpm/store/v3/files/00/11":               "x",
+	})
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"pnp/package.json":                    `{"name": "pnp"}`,
+		"pnp/yarn.lock":                       "__metadata:\n  version: 6\n",
+		"pnp/.yarn/cache/a-npm-1.0.0-abc.zip": "zip",
+		"npm/package.json":                    `{"name": "npm"}`,
+		"npm/package-lock.json":               `{"packages": {}}`,
+		"npm/node_modules/a/package.json":     `{"name": "a", "version": "1.0.0"}`,
+	})
+
+	rep, errs := ScanCaches(nil, root, Options{})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(rep.Projects) != 2 {
+		t.Fatalf("expected 2 projects, got %+v", rep.Projects)
+	}
+	got := map[string]Cache{}
+	for _, c := range rep.Caches {
+		rel, _ := filepath.Rel(home, c.Path)
+		if c.Project != "" {
+			rel, _ = filepath.Rel(root, c.Path)
+		}
+		got[rel] = c
+	}
+	want := map[string]CacheKind{
+		".npm/_cacache":           CacheNPM,
+		"yarn-cache":              CacheYarn,
+		".yarn/berry/cache":       CacheYarnBerry,
+		"pnpm-store":              CachePNPM,
+		".local/share/pnpm/store": CachePNPM,
+		"bun-cache":               CacheBun,
+		"pnp/.yarn/cache":         CacheYarnBerry,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("caches = %+v, want %v", rep.Caches, want)
+	}
+	for rel, kind := range want {
+		c, ok := got[rel]
+		if !ok || c.Kind != kind {
+			t.Errorf("%s: got %+v, want kind %s", rel, c, kind)
+		}
+		if ok && (c.Size == 0 || c.Files == 0 || c.Err != nil) {
+			t.Errorf("%s: not measured: %+v", rel, c)
+		}
+	}
+	if c := got["npm/.yarn/cache"]; c.Path != "" {
+		t.Errorf("unexpected project cache %+v", c)
+	}
+}
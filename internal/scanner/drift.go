@@ -0,0 +1,315 @@
+package scanner
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DriftStatus summarises how a node_modules compares to its lockfile.
+type DriftStatus string
+
+const (
+	DriftInSync DriftStatus = "in-sync"
+
+	// DriftStale means the lockfile asks for packages or versions that are
+	// not installed: the install predates the lockfile and is of no use as
+	// is, which makes it a good deletion candidate.
+	DriftStale DriftStatus = "stale"
+
+	// DriftExtraneous means everything locked is installed, plus packages
+	// the lockfile no longer lists.
+	DriftExtraneous DriftStatus = "extraneous-packages"
+)
+
+// DriftStatuses lists every status in display order.
+var DriftStatuses = []DriftStatus{DriftStale, DriftExtraneous, DriftInSync}
+
+// Drift compares a node_modules against the project's lockfile. Entries are
+// "name@version" (npm nests it as "parent/node_modules/name@version"), and
+// "pattern resolved-url" for yarn classic.
+type Drift struct {
+	Status     DriftStatus
+	Missing    []string // locked but not installed, or installed at another version
+	Extraneous []string // installed but not locked
+}
+
+// checkDrift compares the record a package manager keeps of what it
+// installed into node_modules with the project's lockfile: the hidden
+// node_modules/.package-lock.json for npm, node_modules/.pnpm/lock.yaml for
+// pnpm, and .yarn-state.yml (berry) or .yarn-integrity (classic) for yarn.
+// Optional packages the manager skipped for this platform are not missing.
+// It returns nil when either side is missing or unreadable, or the package
+// manager keeps no such record (bun).
+func checkDrift(path, kind string, project ProjectInfo) *Drift {
+	if kind != KindNodeModules || project.LockfilePath == "" {
+		return nil
+	}
+	var locked, installed map[string]bool
+	var optional map[string]bool
+	switch project.Lockfile {
+	case LockNPM:
+		var ok bool
+		if locked, optional, ok = readNPMLock(project.LockfilePath); !ok {
+			return nil
+		}
+		if installed, _, ok = readNPMLock(filepath.Join(path, ".package-lock.json")); !ok {
+			return nil
+		}
+	case LockPNPM:
+		locked = readPNPMPackages(project.LockfilePath)
+		installed = readPNPMPackages(filepath.Join(path, ".pnpm", "lock.yaml"))
+		optional = readPNPMSkipped(filepath.Join(path, ".modules.yaml"))
+	case LockYarn:
+		if st, err := os.Stat(filepath.Join(path, ".yarn-state.yml")); err == nil && !st.IsDir() {
+			locked, optional = readYarnBerryLock(project.LockfilePath)
+			installed = readYarnState(filepath.Join(path, ".yarn-state.yml"))
+		} else {
+			locked = readYarnClassicLock(project.LockfilePath)
+			installed = readYarnIntegrity(filepath.Join(path, ".yarn-integrity"))
+		}
+	default:
+		return nil
+	}
+	if locked == nil || installed == nil {
+		return nil
+	}
+
+	d := &Drift{}
+	for e := range locked {
+		// optional packages for other platforms are never installed
+		if !installed[e] && !optional[e] {
+			d.Missing = append(d.Missing, e)
+		}
+	}
+	for e := range installed {
+		if !locked[e] {
+			d.Extraneous = append(d.Extraneous, e)
+		}
+	}
+	sort.Strings(d.Missing)
+	sort.Strings(d.Extraneous)
+	switch {
+	case len(d.Missing) > 0:
+		d.Status = DriftStale
+	case len(d.Extraneous) > 0:
+		d.Status = DriftExtraneous
+	default:
+		d.Status = DriftInSync
+	}
+	return d
+}
+
+// readNPMLock reads the "packages" map of a v2/v3 package-lock.json (the
+// hidden .package-lock.json has the same shape). Entries are location@version
+// of everything installed, "b/node_modules/c@1.0.0" for a nested copy;
+// optional reports those marked optional.
+func readNPMLock(path string) (entries, optional map[string]bool, ok bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, false
+	}
+	var lock struct {
+		Packages map[string]struct {
+			Version  string `json:"version"`
+			Optional bool   `json:"optional"`
+			Link     bool   `json:"link"`
+			Resolved string `json:"resolved"`
+		} `json:"packages"`
+	}
+	if json.Unmarshal(data, &lock) != nil || lock.Packages == nil {
+		return nil, nil, false // lockfileVersion 1 has no "packages"
+	}
+	entries, optional = map[string]bool{}, map[string]bool{}
+	for loc, p := range lock.Packages {
+		if !strings.Contains(loc, "node_modules/") {
+			continue // the root project and workspace sources
+		}
+		version := p.Version
+		if p.Link {
+			version = "link:" + p.Resolved
+		}
+		e := strings.TrimPrefix(loc, "node_modules/") + "@" + version
+		entries[e] = true
+		if p.Optional {
+			optional[e] = true
+		}
+	}
+	return entries, optional, true
+}
+
+// readPNPMPackages collects the keys of the top-level "packages:" section of
+// a pnpm lockfile, normalised to name@version without the peer suffix. It
+// returns nil when the file cannot be read.
+func readPNPMPackages(path string) map[string]bool {
+	entries := map[string]bool{}
+	inPackages := false
+	if !scanLines(path, func(line string) {
+		switch {
+		case line == "" || strings.HasPrefix(strings.TrimSpace(line), "#"):
+		case !strings.HasPrefix(line, " "):
+			inPackages = strings.TrimSpace(line) == "packages:"
+		case inPackages && !strings.HasPrefix(line, "   ") && strings.HasSuffix(line, ":"):
+			// package keys sit at exactly two spaces of indentation
+			key := strings.Trim(strings.TrimSuffix(strings.TrimSpace(line), ":"), `'"`)
+			entries[pnpmEntry(key)] = true
+		}
+	}) {
+		return nil
+	}
+	return entries
+}
+
+// readPNPMSkipped collects the "skipped:" list pnpm keeps in
+// node_modules/.modules.yaml: optional packages it did not install because
+// they do not support this platform.
+func readPNPMSkipped(path string) map[string]bool {
+	skipped := map[string]bool{}
+	inSkipped := false
+	scanLines(path, func(line string) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case inSkipped && strings.HasPrefix(trimmed, "- "):
+			skipped[pnpmEntry(strings.Trim(strings.TrimPrefix(trimmed, "- "), `'"`))] = true
+		case !strings.HasPrefix(line, " "):
+			inSkipped = trimmed == "skipped:"
+		}
+	})
+	return skipped
+}
+
+// pnpmEntry turns "/name@1.0.0(peer@2.0.0)" (v6), "name@1.0.0" (v9) or
+// "/name/1.0.0" (v5) into "name@1.0.0".
+func pnpmEntry(key string) string {
+	key = strings.TrimPrefix(key, "/")
+	if i := strings.Index(key, "("); i > 0 {
+		key = key[:i]
+	}
+	if strings.LastIndex(key, "@") > 0 {
+		return key
+	}
+	if i := strings.LastIndex(key, "/"); i > 0 {
+		return key[:i] + "@" + key[i+1:]
+	}
+	return key
+}
+
+// readYarnBerryLock collects the resolution locators of a yarn 2+ lockfile.
+// conditional reports those with "conditions:" (os, cpu or libc), which yarn
+// only installs on matching platforms.
+func readYarnBerryLock(path string) (entries, conditional map[string]bool) {
+	entries, conditional = map[string]bool{}, map[string]bool{}
+	var resolution string
+	var conditions bool
+	flush := func() {
+		if resolution != "" && conditions {
+			conditional[resolution] = true
+		}
+		resolution, conditions = "", false
+	}
+	if !scanLines(path, func(line string) {
+		switch {
+		case line != "" && !strings.HasPrefix(line, " "):
+			flush()
+		case strings.HasPrefix(line, "  resolution: "):
+			resolution = yarnLocator(strings.TrimPrefix(line, "  resolution: "))
+			entries[resolution] = true
+		case strings.HasPrefix(line, "  conditions: "):
+			conditions = true
+		}
+	}) {
+		return nil, nil
+	}
+	flush()
+	return entries, conditional
+}
+
+// readYarnState collects the locators yarn berry installed into node_modules,
+// the top-level keys of .yarn-state.yml. Virtual locators (peer-dependent
+// instances) have no lockfile counterpart and are skipped.
+func readYarnState(path string) map[string]bool {
+	entries := map[string]bool{}
+	if !scanLines(path, func(line string) {
+		if line == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "#") || !strings.HasSuffix(line, ":") {
+			return
+		}
+		if key := yarnLocator(strings.TrimSuffix(line, ":")); key != "__metadata" && !strings.Contains(key, "@virtual:") {
+			entries[key] = true
+		}
+	}) {
+		return nil
+	}
+	return entries
+}
+
+// yarnLocator unquotes a locator and drops the default npm: protocol, so that
+// "foo@npm:1.0.0" reads "foo@1.0.0".
+func yarnLocator(s string) string {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	return strings.Replace(s, "@npm:", "@", 1)
+}
+
+// readYarnClassicLock maps each pattern of a yarn v1 lockfile to its
+// resolved URL, as "pattern resolved".
+func readYarnClassicLock(path string) map[string]bool {
+	entries := map[string]bool{}
+	var patterns []string
+	if !scanLines(path, func(line string) {
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case !strings.HasPrefix(line, " "):
+			patterns = patterns[:0]
+			for _, p := range strings.Split(strings.TrimSuffix(line, ":"), ",") {
+				patterns = append(patterns, strings.Trim(strings.TrimSpace(p), `"`))
+			}
+		case strings.HasPrefix(line, "  resolved "):
+			resolved := strings.Trim(strings.TrimPrefix(line, "  resolved "), `"`)
+			for _, p := range patterns {
+				entries[p+" "+resolved] = true
+			}
+		}
+	}) {
+		return nil
+	}
+	return entries
+}
+
+// readYarnIntegrity reads the lockfileEntries yarn classic recorded in
+// node_modules/.yarn-integrity, in the form readYarnClassicLock uses.
+func readYarnIntegrity(path string) map[string]bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var integrity struct {
+		LockfileEntries map[string]string `json:"lockfileEntries"`
+	}
+	if json.Unmarshal(data, &integrity) != nil {
+		return nil
+	}
+	entries := map[string]bool{}
+	for p, resolved := range integrity.LockfileEntries {
+		entries[p+" "+resolved] = true
+	}
+	return entries
+}
+
+// scanLines calls fn for every line of the file at path and reports whether
+// it could be read.
+func scanLines(path string, fn func(line string)) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		fn(sc.Text())
+	}
+	return sc.Err() == nil
+}
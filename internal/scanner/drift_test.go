@@ -0,0 +1,200 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+}
+
+func TestCheckDrift_NPM(t *testing.T) {
+	const lock = `{"lockfileVersion": 3, "packages": {
+		"": {"name": "app"},
+		"node_modules/a": {"version": "1.1.0"},
+		"node_modules/b": {"version": "2.0.0"},
+		"node_modules/b/node_modules/c": {"version": "1.0.0"},
+		"node_modules/@esbuild/win32-x64": {"version": "0.19.0", "optional": true}}}`
+	cases := []struct {
+		name   string
+		hidden string
+		want   Drift
+	}{
+		{"in-sync", `{"packages": {
+			"node_modules/a": {"version": "1.1.0"},
+			"node_modules/b": {"version": "2.0.0"},
+			"node_modules/b/node_modules/c": {"version": "1.0.0"}}}`,
+			Drift{Status: DriftInSync}},
+		{"stale", `{"packages": {
+			"node_modules/a": {"version": "1.0.0"},
+			"node_modules/b": {"version": "2.0.0"}}}`,
+			Drift{Status: DriftStale, Missing: []string{"a@1.1.0", "b/node_modules/c@1.0.0"}, Extraneous: []string{"a@1.0.0"}}},
+		{"extraneous", `{"packages": {
+			"node_modules/a": {"version": "1.1.0"},
+			"node_modules/b": {"version": "2.0.0"},
+			"node_modules/b/node_modules/c": {"version": "1.0.0"},
+			"node_modules/left-pad": {"version": "1.3.0"}}}`,
+			Drift{Status: DriftExtraneous, Extraneous: []string{"left-pad@1.3.0"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"package.json":                    `{"name": "app"}`,
+				"package-lock.json":               lock,
+				"node_modules/.package-lock.json": c.hidden,
+			})
+			got := checkDrift(filepath.Join(dir, "node_modules"), KindNodeModules, readProject(dir))
+			if got == nil || !reflect.DeepEqual(*got, c.want) {
+				t.Fatalf("drift = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCheckDrift_PNPM(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"package.json": `{"name": "app"}`,
+		"pnpm-lock.yaml": `lockfileVersion: '6.0'
+
+dependencies:
+  a:
+    specifier: ^1.0.0
+    version: 1.1.0
+
+packages:
+
+  /a@1.1.0:
+    resolution: {integrity: sha512-x}
+    dev: false
+
+  /@scope/b@2.0.0(a@1.1.0):
+    resolution: {integrity: sha512-y}
+
+  /@esbuild/darwin-arm64@0.19.0:
+    resolution: {integrity: sha512-w}
+    cpu: [arm64]
+    os: [darwin]
+    requiresBuild: true
+    optional: true
+`,
+		"node_modules/.modules.yaml": `hoistPattern:
+  - '*'
+layoutVersion: 5
+packageManager: pnpm@8.15.0
+skipped:
+  - /@esbuild/darwin-arm64@0.19.0
+storeDir: /home/u/.local/share/pnpm/store/v3
+`,
+		"node_modules/.pnpm/lock.yaml": `lockfileVersion: '6.0'
+
+packages:
+
+  /a@1.0.0:
+    resolution: {integrity: sha512-z}
+
+  /@scope/b@2.0.0(a@1.0.0):
+    resolution: {integrity: sha512-y}
+`,
+	})
+	got := checkDrift(filepath.Join(dir, "node_modules"), KindNodeModules, readProject(dir))
+	want := Drift{Status: DriftStale, Missing: []string{"a@1.1.0"}, Extraneous: []string{"a@1.0.0"}}
+	if got == nil || !reflect.DeepEqual(*got, want) {
+		t.Fatalf("drift = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckDrift_Yarn(t *testing.T) {
+	berry := t.TempDir()
+	writeFiles(t, berry, map[string]string{
+		"package.json": `{"name": "app"}`,
+		"yarn.lock": `__metadata:
+  version: 6
+
+"a@npm:^1.0.0":
+  version: 1.1.0
+  resolution: "a@npm:1.1.0"
+
+"@esbuild/darwin-arm64@npm:0.19.0":
+  version: 0.19.0
+  resolution: "@esbuild/darwin-arm64@npm:0.19.0"
+  conditions: os=darwin & cpu=arm64
+  languageName: node
+  linkType: hard
+
+"app@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "app@workspace:."
+`,
+		"node_modules/.yarn-state.yml": `# Warning: This file is automatically generated.
+
+__metadata:
+  version: 1
+  nmMode: classic
+
+"a@npm:1.1.0":
+  locations:
+    - "node_modules/a"
+
+"app@workspace:.":
+  locations:
+    - ""
+
+"b@virtual:abc#npm:1.0.0":
+  locations:
+    - "node_modules/b"
+`,
+	})
+	got := checkDrift(filepath.Join(berry, "node_modules"), KindNodeModules, readProject(berry))
+	if got == nil || got.Status != DriftInSync {
+		t.Fatalf("berry drift = %+v, want in-sync", got)
+	}
+
+	classic := t.TempDir()
+	writeFiles(t, classic, map[string]string{
+		"package.json": `{"name": "app"}`,
+		"yarn.lock": `# yarn lockfile v1
+
+
+a@^1.0.0, a@^1.1.0:
+  version "1.1.0"
+  resolved "https://registry.yarnpkg.com/a/-/a-1.1.0.tgz#abc"
+`,
+		"node_modules/.yarn-integrity": `{"lockfileEntries": {
+			"a@^1.0.0": "https://registry.yarnpkg.com/a/-/a-1.0.0.tgz#def"}}`,
+	})
+	got = checkDrift(filepath.Join(classic, "node_modules"), KindNodeModules, readProject(classic))
+	want := Drift{
+		Status:     DriftStale,
+		Missing:    []string{"a@^1.0.0 https://registry.yarnpkg.com/a/-/a-1.1.0.tgz#abc", "a@^1.1.0 https://registry.yarnpkg.com/a/-/a-1.1.0.tgz#abc"},
+		Extraneous: []string{"a@^1.0.0 https://registry.yarnpkg.com/a/-/a-1.0.0.tgz#def"},
+	}
+	if got == nil || !reflect.DeepEqual(*got, want) {
+		t.Fatalf("classic drift = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckDrift_NothingToCompare(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"package.json":                `{"name": "app"}`,
+		"package-lock.json":           `{"packages": {}}`,
+		"node_modules/a/package.json": `{}`,
+	})
+	if d := checkDrift(filepath.Join(dir, "node_modules"), KindNodeModules, readProject(dir)); d != nil {
+		t.Fatalf("expected nil drift without a hidden lockfile, got %+v", d)
+	}
+}
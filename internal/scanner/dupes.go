@@ -0,0 +1,236 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PackageCopy is one installed instance of a package.
+type PackageCopy struct {
+	Path   string
+	Size   int64 // apparent bytes, nested node_modules excluded
+	Unique int64 // allocated bytes not shared (hardlinked) with a copy measured earlier
+}
+
+// Duplicate is a name@version installed more than once.
+type Duplicate struct {
+	Name    string
+	Version string
+	Count   int
+	Size    int64 // apparent bytes of one copy (the largest)
+	Wasted  int64 // apparent bytes beyond that one copy
+
+	// StoreSavings estimates what a shared content-addressed store (as used
+	// by pnpm) would free: allocated bytes of every copy but one, minus what
+	// is already hardlinked.
+	StoreSavings int64
+
+	Copies []PackageCopy
+}
+
+// DuplicateReport summarises packages installed more than once across a set
+// of node_modules directories.
+type DuplicateReport struct {
+	Packages     int         // package instances indexed
+	Duplicates   []Duplicate // most wasted first
+	Wasted       int64
+	StoreSavings int64
+}
+
+// FindDuplicates indexes name@version for every package in the given
+// node_modules directories, nested node_modules and pnpm's .pnpm virtual
+// store included, and reports those installed more than once. Symlinked
+// packages are skipped so that a linked install is not counted twice. It
+// also returns the copies that could not be measured; when ctx is cancelled
+// the report is empty.
+func FindDuplicates(ctx context.Context, dirs []string, opts Options) (DuplicateReport, []*ScanError) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	opts = opts.withDefaults()
+
+	type instance struct {
+		key  string
+		name string
+		ver  string
+		copy PackageCopy
+	}
+	var insts []instance
+	for _, dir := range dirs {
+		indexPackages(dir, func(name, version, path string) {
+			insts = append(insts, instance{key: name + "@" + version, name: name, ver: version, copy: PackageCopy{Path: path}})
+		})
+	}
+	counts := map[string]int{}
+	for _, in := range insts {
+		counts[in.key]++
+	}
+
+	// Only copies of duplicated packages need measuring.
+	claims := newInodeClaims()
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []*ScanError
+	worker := func() {
+		defer wg.Done()
+		for i := range jobs {
+			c := &insts[i].copy
+			var err error
+			c.Size, c.Unique, err = packageUsage(ctx, c.Path, claims)
+			if err != nil && ctx.Err() == nil {
+				mu.Lock()
+				errs = append(errs, newScanError(OpSize, c.Path, err))
+				mu.Unlock()
+			}
+		}
+	}
+	wg.Add(opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		go worker()
+	}
+	for i, in := range insts {
+		if ctx.Err() != nil {
+			break
+		}
+		if counts[in.key] > 1 {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return DuplicateReport{}, []*ScanError{newScanError(OpSize, "", err)}
+	}
+
+	byKey := map[string]*Duplicate{}
+	rep := DuplicateReport{Packages: len(insts)}
+	for _, in := range insts {
+		if counts[in.key] < 2 {
+			continue
+		}
+		d, ok := byKey[in.key]
+		if !ok {
+			d = &Duplicate{Name: in.name, Version: in.ver}
+			byKey[in.key] = d
+		}
+		d.Copies = append(d.Copies, in.copy)
+	}
+	for _, d := range byKey {
+		d.Count = len(d.Copies)
+		var total, unique, maxUnique int64
+		for _, c := range d.Copies {
+			total += c.Size
+			unique += c.Unique
+			if c.Size > d.Size {
+				d.Size = c.Size
+			}
+			if c.Unique > maxUnique {
+				maxUnique = c.Unique
+			}
+		}
+		d.Wasted = total - d.Size
+		d.StoreSavings = unique - maxUnique
+		sort.Slice(d.Copies, func(i, j int) bool { return d.Copies[i].Path < d.Copies[j].Path })
+		rep.Duplicates = append(rep.Duplicates, *d)
+		rep.Wasted += d.Wasted
+		rep.StoreSavings += d.StoreSavings
+	}
+	sort.Slice(rep.Duplicates, func(i, j int) bool {
+		a, b := rep.Duplicates[i], rep.Duplicates[j]
+		if a.Wasted == b.Wasted {
+			return a.Name+"@"+a.Version < b.Name+"@"+b.Version
+		}
+		return a.Wasted > b.Wasted
+	})
+	return rep, errs
+}
+
+// indexPackages calls found for every versioned package below the
+// node_modules directory nm, recursing into nested node_modules and into
+// pnpm's .pnpm/<name@version>/node_modules layout.
+func indexPackages(nm string, found func(name, version, path string)) {
+	entries, err := os.ReadDir(nm)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		path := filepath.Join(nm, name)
+		switch {
+		case !e.IsDir():
+			// symlinks (pnpm, workspaces) and files
+		case name == ".pnpm":
+			store, _ := os.ReadDir(path)
+			for _, s := range store {
+				if s.IsDir() {
+					indexPackages(filepath.Join(path, s.Name(), "node_modules"), found)
+				}
+			}
+		case strings.HasPrefix(name, "."):
+			// .bin, .cache and friends
+		case strings.HasPrefix(name, "@"):
+			scoped, _ := os.ReadDir(path)
+			for _, s := range scoped {
+				if s.IsDir() {
+					indexPackage(name+"/"+s.Name(), filepath.Join(path, s.Name()), found)
+				}
+			}
+		default:
+			indexPackage(name, path, found)
+		}
+	}
+}
+
+func indexPackage(dirName, path string, found func(name, version, path string)) {
+	if data, err := os.ReadFile(filepath.Join(path, "package.json")); err == nil {
+		var pj packageJSON
+		if json.Unmarshal(data, &pj) == nil && pj.Version != "" {
+			name := pj.Name
+			if name == "" {
+				name = dirName
+			}
+			found(name, pj.Version, path)
+		}
+	}
+	indexPackages(filepath.Join(path, "node_modules"), found)
+}
+
+// packageUsage measures one package copy without its nested node_modules.
+// unique counts allocated bytes of inodes not yet claimed by another copy.
+func packageUsage(ctx context.Context, dir string, claims *inodeClaims) (size, unique int64, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if d.Name() == "node_modules" && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		size += info.Size()
+		if st, ok := statOf(info); ok {
+			if claims.claim(st.id) {
+				unique += st.allocated
+			}
+		} else {
+			unique += info.Size()
+		}
+		return nil
+	})
+	return size, unique, err
+}
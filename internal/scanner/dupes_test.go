@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	root := t.TempDir()
+	const manifest = `{"name":"typescript","version":"5.0.0"}`
+	install := func(dir, manifest string, size int64) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(manifest), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		writeFileOfSize(t, filepath.Join(dir, "index.js"), size)
+	}
+	nm1 := filepath.Join(root, "p1", "node_modules")
+	nm2 := filepath.Join(root, "p2", "node_modules")
+	install(filepath.Join(nm1, "typescript"), manifest, 1000)
+	install(filepath.Join(nm2, "typescript"), manifest, 1000)
+	install(filepath.Join(nm2, "a"), `{"name":"a","version":"1.0.0"}`, 10)
+	install(filepath.Join(nm2, "a", "node_modules", "typescript"), manifest, 1000)
+	install(filepath.Join(nm2, ".pnpm", "typescript@5.0.0", "node_modules", "typescript"), manifest, 1000)
+
+	rep, errs := FindDuplicates(nil, []string{nm1, nm2}, Options{})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if rep.Packages != 5 {
+		t.Fatalf("expected 5 package instances, got %d", rep.Packages)
+	}
+	if len(rep.Duplicates) != 1 {
+		t.Fatalf("expected 1 duplicate, got %+v", rep.Duplicates)
+	}
+	d := rep.Duplicates[0]
+	copySize := int64(1000 + len(manifest))
+	if d.Name != "typescript" || d.Version != "5.0.0" || d.Count != 4 || d.Size != copySize {
+		t.Fatalf("unexpected duplicate: %+v", d)
+	}
+	if d.Wasted != 3*copySize || rep.Wasted != d.Wasted {
+		t.Fatalf("wasted = %d, want %d", d.Wasted, 3*copySize)
+	}
+	if d.StoreSavings <= 0 {
+		t.Fatalf("expected store savings for independent copies, got %d", d.StoreSavings)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	// copies that already share inodes would gain nothing from a store
+	nm3 := filepath.Join(root, "p3", "node_modules")
+	src := filepath.Join(nm1, "typescript")
+	dst := filepath.Join(nm3, "typescript")
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for _, f := range []string{"package.json", "index.js"} {
+		if err := os.Link(filepath.Join(src, f), filepath.Join(dst, f)); err != nil {
+			t.Fatalf("link: %v", err)
+		}
+	}
+	rep, errs = FindDuplicates(nil, []string{nm1, nm3}, Options{})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(rep.Duplicates) != 1 || rep.Duplicates[0].Wasted != copySize || rep.StoreSavings != 0 {
+		t.Fatalf("hardlinked copies: %+v", rep)
+	}
+}
@@ -0,0 +1,93 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+)
+
+// ErrorKind classifies a ScanError by its cause.
+type ErrorKind string
+
+const (
+	ErrPermission ErrorKind = "permission"
+	ErrNotExist   ErrorKind = "not-exist"
+	ErrLoop       ErrorKind = "loop" // too many levels of symbolic links
+	ErrCancelled  ErrorKind = "cancelled"
+	ErrIO         ErrorKind = "io" // anything else
+)
+
+// ErrorKinds lists every kind in display order.
+var ErrorKinds = []ErrorKind{ErrPermission, ErrNotExist, ErrLoop, ErrCancelled, ErrIO}
+
+// Operations reported in ScanError.Op.
+const (
+	OpWalk = "walk" // reading a directory during discovery
+	OpSize = "size" // measuring a target
+)
+
+// ScanError is a failure at one path during a scan.
+type ScanError struct {
+	Path string
+	Op   string
+	Kind ErrorKind
+	Err  error
+}
+
+// newScanError wraps err with the path and operation it failed at. An err
+// that already is a *ScanError is returned unchanged.
+func newScanError(op, path string, err error) *ScanError {
+	var se *ScanError
+	if errors.As(err, &se) {
+		return se
+	}
+	return &ScanError{Path: path, Op: op, Kind: classifyError(err), Err: err}
+}
+
+func classifyError(err error) ErrorKind {
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrCancelled
+	case errors.Is(err, fs.ErrPermission):
+		return ErrPermission
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrNotExist
+	case errors.Is(err, syscall.ELOOP):
+		return ErrLoop
+	default:
+		return ErrIO
+	}
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("%s error at %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *ScanError) Unwrap() error { return e.Err }
+
+// MarshalJSON renders the underlying error as its message; a bare error
+// value would otherwise serialize to {}.
+func (e *ScanError) MarshalJSON() ([]byte, error) {
+	var msg string
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	return json.Marshal(struct {
+		Path string
+		Op   string
+		Kind ErrorKind
+		Err  string
+	}{e.Path, e.Op, e.Kind, msg})
+}
+
+// GroupErrors buckets errs by kind, keeping their order within a kind.
+func GroupErrors(errs []*ScanError) map[ErrorKind][]*ScanError {
+	groups := make(map[ErrorKind][]*ScanError)
+	for _, e := range errs {
+		groups[e.Kind] = append(groups[e.Kind], e)
+	}
+	return groups
+}
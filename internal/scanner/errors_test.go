@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want ErrorKind
+	}{
+		{&fs.PathError{Op: "open", Path: "/x", Err: syscall.EACCES}, ErrPermission},
+		{&fs.PathError{Op: "lstat", Path: "/x", Err: syscall.ENOENT}, ErrNotExist},
+		{&fs.PathError{Op: "stat", Path: "/x", Err: syscall.ELOOP}, ErrLoop},
+		{fmt.Errorf("wrapped: %w", context.Canceled), ErrCancelled},
+		{errors.New("disk on fire"), ErrIO},
+	}
+	for _, c := range cases {
+		if got := classifyError(c.err); got != c.want {
+			t.Errorf("classifyError(%v) = %q, want %q", c.err, got, c.want)
+		}
+	}
+}
+
+func TestScanNodeModules_WalkErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "gone")
+	_, _, errs := ScanNodeModules(nil, missing, Options{})
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	e := errs[0]
+	if e.Path != missing || e.Op != OpWalk || e.Kind != ErrNotExist || !errors.Is(e, os.ErrNotExist) {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+}
+
+func TestScanError_JSON(t *testing.T) {
+	r := ResultItem{Path: "/p/node_modules", Err: newScanError(OpSize, "/p/node_modules", &fs.PathError{Op: "open", Path: "/p/node_modules/x", Err: syscall.EACCES})}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var got struct {
+		Err struct{ Path, Op, Kind, Err string }
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Err.Path != "/p/node_modules" || got.Err.Op != OpSize || got.Err.Kind != string(ErrPermission) || got.Err.Err != "open /p/node_modules/x: permission denied" {
+		t.Fatalf("unexpected JSON: %s", data)
+	}
+
+	data, _ = json.Marshal(ResultItem{Path: "/ok"})
+	var ok struct{ Err *struct{} }
+	if err := json.Unmarshal(data, &ok); err != nil || ok.Err != nil {
+		t.Fatalf("expected null Err, got %s", data)
+	}
+}
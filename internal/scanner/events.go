@@ -0,0 +1,71 @@
+package scanner
+
+import "sync"
+
+// Event is sent on the ScanNodeModulesStream channel. It is one of
+// Discovered, Sized or Error.
+type Event interface {
+	event()
+}
+
+// Discovered reports a target as soon as the walk finds it. Project metadata
+// and Health are filled in, and LastActivity when an age filter is set;
+// sizes and the other details are not known yet.
+type Discovered struct{ ResultItem }
+
+// Sized reports the measured result of a previously discovered target with
+// all its details. A target that could not be measured is sized too, with
+// Err set, after the Error event.
+type Sized struct{ ResultItem }
+
+// Error reports a failure: either a discovered target that could not be
+// measured (Op is OpSize) or a directory the walk could not read (OpWalk).
+type Error struct{ *ScanError }
+
+func (Discovered) event() {}
+func (Sized) event()      {}
+func (Error) event()      {}
+
+// pending is an unbounded FIFO between discovery and the size workers, so a
+// slow dirSize never holds up the walk.
+type pending struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []ResultItem
+	closed bool
+}
+
+func newPending() *pending {
+	q := &pending{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *pending) push(r ResultItem) {
+	q.mu.Lock()
+	q.items = append(q.items, r)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// close wakes every waiting pop once the queue drains.
+func (q *pending) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+func (q *pending) pop() (ResultItem, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return ResultItem{}, false
+	}
+	r := q.items[0]
+	q.items = q.items[1:]
+	return r, true
+}
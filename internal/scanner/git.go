@@ -0,0 +1,341 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitInfo describes the git work tree a result's project lives in. It is
+// read straight from the .git directory; git itself is never run.
+type GitInfo struct {
+	Root       string    // work tree root
+	LastCommit time.Time // committer time of HEAD; zero when unknown
+
+	// Tracked reports that files below the target are in the git index, i.e.
+	// the directory is vendored on purpose and must not be deleted casually.
+	Tracked bool
+}
+
+// gitRepo is what one scan learns about a repository, shared by every
+// result inside it. load fills lastCommit and index once.
+type gitRepo struct {
+	root, gitDir string
+	once         sync.Once
+	lastCommit   time.Time
+	index        []string // sorted paths of the index; nil when unreadable
+}
+
+func (r *gitRepo) load() {
+	r.lastCommit = lastCommitTime(r.gitDir)
+	if paths, err := readGitIndex(filepath.Join(r.gitDir, "index")); err == nil {
+		r.index = paths
+	}
+}
+
+// gitRepos caches repositories by work tree root for the length of a scan.
+// The lock only guards the map; each repository is read outside it, once.
+type gitRepos struct {
+	mu    sync.Mutex
+	repos map[string]*gitRepo
+}
+
+func newGitRepos() *gitRepos {
+	return &gitRepos{repos: make(map[string]*gitRepo)}
+}
+
+// lookup finds the repository containing target by looking for .git upward
+// from dir, and reports whether target is tracked in it. It returns nil
+// outside a repository.
+func (g *gitRepos) lookup(dir, target string) *GitInfo {
+	root, gitDir, ok := findGitDir(dir)
+	if !ok {
+		return nil
+	}
+	g.mu.Lock()
+	repo, ok := g.repos[root]
+	if !ok {
+		repo = &gitRepo{root: root, gitDir: gitDir}
+		g.repos[root] = repo
+	}
+	g.mu.Unlock()
+	repo.once.Do(repo.load)
+	return &GitInfo{Root: root, LastCommit: repo.lastCommit, Tracked: repo.tracks(target)}
+}
+
+// tracks reports whether the index has an entry below dir.
+func (r *gitRepo) tracks(dir string) bool {
+	rel, err := filepath.Rel(r.root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	prefix := filepath.ToSlash(rel) + "/"
+	i := sort.SearchStrings(r.index, prefix)
+	return i < len(r.index) && strings.HasPrefix(r.index[i], prefix)
+}
+
+// GitTracking answers TrackedInGit for many directories, reading the index
+// of each repository they live in only once. It is safe for concurrent use.
+type GitTracking struct {
+	repos *gitRepos
+}
+
+// NewGitTracking returns an empty GitTracking.
+func NewGitTracking() *GitTracking {
+	return &GitTracking{repos: newGitRepos()}
+}
+
+// Tracked reports whether files below dir are in the index of the git work
+// tree containing it.
+func (g *GitTracking) Tracked(dir string) bool {
+	info := g.repos.lookup(filepath.Dir(dir), dir)
+	return info != nil && info.Tracked
+}
+
+// TrackedInGit reports whether files below dir are in the index of the git
+// work tree containing it. Use a GitTracking to check many directories.
+func TrackedInGit(dir string) bool {
+	return NewGitTracking().Tracked(dir)
+}
+
+// findGitDir walks up from dir to the first directory holding .git, either
+// a directory or a "gitdir: <path>" file (worktrees, submodules).
+func findGitDir(dir string) (root, gitDir string, ok bool) {
+	for d := dir; ; d = filepath.Dir(d) {
+		p := filepath.Join(d, ".git")
+		if st, err := os.Stat(p); err == nil {
+			if st.IsDir() {
+				return d, p, true
+			}
+			if data, err := os.ReadFile(p); err == nil {
+				if s := strings.TrimSpace(string(data)); strings.HasPrefix(s, "gitdir: ") {
+					g := strings.TrimPrefix(s, "gitdir: ")
+					if !filepath.IsAbs(g) {
+						g = filepath.Join(d, g)
+					}
+					return d, g, true
+				}
+			}
+		}
+		if filepath.Dir(d) == d {
+			return "", "", false
+		}
+	}
+}
+
+// commonDir is where refs and objects live; a linked worktree's git dir
+// points to it from its commondir file.
+func commonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	c := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(c) {
+		c = filepath.Join(gitDir, c)
+	}
+	return c
+}
+
+// lastCommitTime reads the time HEAD last moved from the reflog, falling
+// back to the committer time of the HEAD commit when it is a loose object.
+func lastCommitTime(gitDir string) time.Time {
+	if t, ok := reflogTime(filepath.Join(gitDir, "logs", "HEAD")); ok {
+		return t
+	}
+	hash, ok := resolveHead(gitDir)
+	if !ok {
+		return time.Time{}
+	}
+	t, _ := looseCommitTime(commonDir(gitDir), hash)
+	return t
+}
+
+// reflogTime parses the timestamp of the last reflog line:
+// "<old> <new> Name <email> 1700000000 +0100\tcommit: message".
+func reflogTime(path string) (time.Time, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	line := lines[len(lines)-1]
+	if i := strings.IndexByte(line, '\t'); i >= 0 {
+		line = line[:i]
+	}
+	return signatureTime(line)
+}
+
+// signatureTime reads the unix time after the email of a git signature.
+func signatureTime(sig string) (time.Time, bool) {
+	i := strings.LastIndexByte(sig, '>')
+	if i < 0 {
+		return time.Time{}, false
+	}
+	fields := strings.Fields(sig[i+1:])
+	if len(fields) == 0 {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+// resolveHead returns the commit hash HEAD points to, following one
+// symbolic ref through loose refs and packed-refs.
+func resolveHead(gitDir string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", false
+	}
+	head := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(head, "ref: ") {
+		return head, head != "" // detached
+	}
+	ref := strings.TrimPrefix(head, "ref: ")
+	common := commonDir(gitDir)
+	if data, err := os.ReadFile(filepath.Join(common, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(data)), true
+	}
+	f, err := os.Open(filepath.Join(common, "packed-refs"))
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if hash, name, ok := strings.Cut(sc.Text(), " "); ok && name == ref {
+			return hash, true
+		}
+	}
+	return "", false
+}
+
+// looseCommitTime reads the committer time of a commit stored as a loose
+// object. Packed objects are not read.
+func looseCommitTime(common, hash string) (time.Time, bool) {
+	if len(hash) < 3 {
+		return time.Time{}, false
+	}
+	f, err := os.Open(filepath.Join(common, "objects", hash[:2], hash[2:]))
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer f.Close()
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return time.Time{}, false
+	}
+	defer zr.Close()
+	// the header lines of a commit are all we need
+	data, _ := io.ReadAll(io.LimitReader(zr, 64*1024))
+	if !bytes.HasPrefix(data, []byte("commit ")) {
+		return time.Time{}, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "committer ") {
+			return signatureTime(line)
+		}
+		if line == "" {
+			break
+		}
+	}
+	return time.Time{}, false
+}
+
+var errBadIndex = errors.New("malformed git index")
+
+// readGitIndex returns the sorted paths of a git index file (versions 2 to
+// 4, SHA-1 repositories).
+func readGitIndex(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, errBadIndex
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, errBadIndex
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+	const statLen = 62 // stat data, object id and flags
+	if n := uint32(len(data) / statLen); count > n {
+		return nil, errBadIndex
+	}
+	paths := make([]string, 0, count)
+	off := 12
+	prev := ""
+	for i := uint32(0); i < count; i++ {
+		start := off
+		if off+statLen > len(data) {
+			return nil, errBadIndex
+		}
+		flags := binary.BigEndian.Uint16(data[off+60 : off+62])
+		off += statLen
+		if version >= 3 && flags&0x4000 != 0 {
+			off += 2 // extended flags
+			if off > len(data) {
+				return nil, errBadIndex
+			}
+		}
+		var name string
+		if version == 4 {
+			// prefix-compressed: drop n bytes of the previous path, then a
+			// NUL-terminated suffix, no padding
+			n, l := indexVarint(data[off:])
+			if l == 0 || n > len(prev) {
+				return nil, errBadIndex
+			}
+			off += l
+			end := bytes.IndexByte(data[off:], 0)
+			if end < 0 {
+				return nil, errBadIndex
+			}
+			name = prev[:len(prev)-n] + string(data[off:off+end])
+			off += end + 1
+		} else {
+			end := bytes.IndexByte(data[off:], 0)
+			if end < 0 {
+				return nil, errBadIndex
+			}
+			name = string(data[off : off+end])
+			// entries are NUL-padded to a multiple of eight bytes
+			off = start + (off-start+end+8)&^7
+		}
+		paths = append(paths, name)
+		prev = name
+	}
+	return paths, nil
+}
+
+// indexVarint decodes git's offset varint, returning the value and the
+// number of bytes read (0 on truncated input).
+func indexVarint(b []byte) (int, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	val := int(b[0] & 0x7f)
+	i := 1
+	for b[i-1]&0x80 != 0 {
+		if i >= len(b) {
+			return 0, 0
+		}
+		val = ((val + 1) << 7) | int(b[i]&0x7f)
+		i++
+	}
+	return val, i
+}
@@ -0,0 +1,140 @@
+package scanner
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gitFixture creates a repository with a vendored (tracked) node_modules in
+// "vendored" and an ignored one in "app", committed at the given time.
+func gitFixture(t *testing.T, when time.Time) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":                               "app/node_modules/\n",
+		"app/package.json":                         `{"name": "app"}`,
+		"app/node_modules/a/index.js":              "x",
+		"vendored/package.json":                    `{"name": "vendored"}`,
+		"vendored/node_modules/b/index.js":         "y",
+		"vendored/node_modules/b/lib/deep/file.js": "z",
+	})
+	stamp := when.Format(time.RFC3339)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+stamp, "GIT_COMMITTER_DATE="+stamp)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return root
+}
+
+func TestScanNodeModules_Git(t *testing.T) {
+	when := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	root := gitFixture(t, when)
+
+	results, _, errs := ScanNodeModules(nil, root, Options{})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for _, r := range results {
+		vendored := filepath.Base(filepath.Dir(r.Path)) == "vendored"
+		if r.Git == nil {
+			t.Fatalf("%s: missing git info", r.Path)
+		}
+		if r.Git.Tracked != vendored || r.Protected != vendored {
+			t.Errorf("%s: tracked=%v protected=%v, want %v", r.Path, r.Git.Tracked, r.Protected, vendored)
+		}
+		if !r.Git.LastCommit.Equal(when) {
+			t.Errorf("%s: last commit %v, want %v", r.Path, r.Git.LastCommit, when)
+		}
+	}
+}
+
+func TestGit_IndexV4AndLooseCommit(t *testing.T) {
+	when := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	root := gitFixture(t, when)
+	cmd := exec.Command("git", "update-index", "--index-version", "4")
+	cmd.Dir = root
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("update-index: %v\n%s", err, out)
+	}
+	// without the reflog the time comes from the loose HEAD commit
+	if err := os.RemoveAll(filepath.Join(root, ".git", "logs")); err != nil {
+		t.Fatalf("remove logs: %v", err)
+	}
+
+	if !TrackedInGit(filepath.Join(root, "vendored", "node_modules")) {
+		t.Fatalf("vendored node_modules not reported as tracked with index v4")
+	}
+	if TrackedInGit(filepath.Join(root, "app", "node_modules")) {
+		t.Fatalf("ignored node_modules reported as tracked")
+	}
+	if got := lastCommitTime(filepath.Join(root, ".git")); !got.Equal(when) {
+		t.Fatalf("last commit %v, want %v", got, when)
+	}
+}
+
+func TestGitTracking_SharesRepositories(t *testing.T) {
+	root := gitFixture(t, time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC))
+	g := NewGitTracking()
+	want := map[string]bool{
+		filepath.Join(root, "vendored", "node_modules"): true,
+		filepath.Join(root, "app", "node_modules"):      false,
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for dir, tracked := range want {
+			wg.Add(1)
+			go func(dir string, tracked bool) {
+				defer wg.Done()
+				if got := g.Tracked(dir); got != tracked {
+					t.Errorf("%s: tracked %v, want %v", dir, got, tracked)
+				}
+			}(dir, tracked)
+		}
+	}
+	wg.Wait()
+	if n := len(g.repos.repos); n != 1 {
+		t.Fatalf("read %d repositories, want the one shared by every target", n)
+	}
+}
+
+func TestGit_OutsideRepository(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, ok := findGitDir(dir); ok {
+		t.Skip("temp dir is inside a git work tree")
+	}
+	if info := newGitRepos().lookup(dir, filepath.Join(dir, "node_modules")); info != nil {
+		t.Fatalf("expected no git info, got %+v", info)
+	}
+}
+
+func TestGit_TruncatedIndex(t *testing.T) {
+	// a v3 entry with extended flags, cut off right after its stat data
+	data := append([]byte("DIRC"), 0, 0, 0, 3, 0, 0, 0, 1)
+	entry := make([]byte, 62)
+	entry[60] = 0x40
+	path := filepath.Join(t.TempDir(), "index")
+	if err := os.WriteFile(path, append(data, entry...), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := readGitIndex(path); err != errBadIndex {
+		t.Fatalf("expected errBadIndex, got %v", err)
+	}
+}
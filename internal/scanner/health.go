@@ -0,0 +1,71 @@
+package scanner
+
+import (
+	"os"
+	"strings"
+)
+
+// Health classifies the state of a found target.
+type Health string
+
+const (
+	HealthOK Health = "ok"
+
+	// HealthOrphan is a node_modules without a sibling package.json: the
+	// project was moved or deleted, or a clone failed half way. Always safe to
+	// delete.
+	HealthOrphan Health = "orphan"
+
+	// HealthEmpty is a target with nothing in it; for node_modules, no
+	// packages (dot entries such as .cache do not count).
+	HealthEmpty Health = "empty"
+
+	// HealthIncomplete is a node_modules next to an npm, pnpm or yarn
+	// lockfile that lacks the marker the package manager writes once an
+	// install finishes, typically an interrupted install.
+	HealthIncomplete Health = "incomplete"
+)
+
+// installMarkers are written into node_modules by package managers at the
+// end of an install. .yarn-integrity is yarn classic's.
+var installMarkers = []string{".package-lock.json", ".modules.yaml", ".yarn-state.yml", ".yarn-integrity"}
+
+// checkHealth classifies the target at path. It only reads the target's top
+// level, so it is cheap enough to run on every discovery.
+func checkHealth(path, kind string, project ProjectInfo) Health {
+	if kind != KindNodeModules {
+		if entries, err := os.ReadDir(path); err == nil && len(entries) == 0 {
+			return HealthEmpty
+		}
+		return HealthOK
+	}
+	if !project.HasPackageJSON {
+		return HealthOrphan
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return HealthOK // unreadable; the size error reports it
+	}
+	var packages int
+	markers := map[string]bool{}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			markers[e.Name()] = true
+			continue
+		}
+		packages++
+	}
+	if packages == 0 {
+		return HealthEmpty
+	}
+	switch project.Lockfile {
+	case LockNPM, LockPNPM, LockYarn:
+		for _, m := range installMarkers {
+			if markers[m] {
+				return HealthOK
+			}
+		}
+		return HealthIncomplete
+	}
+	return HealthOK
+}
@@ -0,0 +1,60 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckHealth(t *testing.T) {
+	root := t.TempDir()
+	mk := func(rel string, files ...string) string {
+		dir := filepath.Join(root, rel)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		for _, f := range files {
+			p := filepath.Join(dir, f)
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			if err := os.WriteFile(p, []byte("{}"), 0o644); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+		}
+		return dir
+	}
+	mk("ok", "package.json", "package-lock.json", "node_modules/.package-lock.json", "node_modules/a/index.js")
+	mk("orphan", "node_modules/a/index.js")
+	mk("empty", "package.json", "node_modules/.cache/x")
+	mk("incomplete", "package.json", "pnpm-lock.yaml", "node_modules/a/index.js")
+	mk("nolock", "package.json", "node_modules/a/index.js")
+	mk("yarn1", "package.json", "yarn.lock", "node_modules/.yarn-integrity", "node_modules/a/index.js")
+
+	results, _, errs := ScanNodeModules(nil, root, Options{})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	want := map[string]Health{
+		"ok":         HealthOK,
+		"orphan":     HealthOrphan,
+		"empty":      HealthEmpty,
+		"incomplete": HealthIncomplete,
+		"nolock":     HealthOK,
+		"yarn1":      HealthOK,
+	}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(results))
+	}
+	for _, r := range results {
+		proj := filepath.Base(filepath.Dir(r.Path))
+		if r.Health != want[proj] {
+			t.Errorf("%s: health = %q, want %q", proj, r.Health, want[proj])
+		}
+	}
+
+	dist := mk("app/dist")
+	if h := checkHealth(dist, "dist", ProjectInfo{HasPackageJSON: true}); h != HealthEmpty {
+		t.Errorf("empty dist: health = %q, want %q", h, HealthEmpty)
+	}
+}
@@ -0,0 +1,233 @@
+package scanner
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ignoreFileName is the per-directory exclude file honoured when
+// Options.IgnoreFiles is set. It uses .gitignore syntax and scoping.
+const ignoreFileName = ".nmmignore"
+
+// ignoreRule is one compiled gitignore-style pattern.
+type ignoreRule struct {
+	segs     []string // slash-separated pattern segments; "**" spans any number
+	negate   bool     // "!pat" re-includes a previously excluded path
+	dirOnly  bool     // "pat/" only matches directories
+	anchored bool     // pattern contains a slash, so it matches from the base dir
+}
+
+// parseIgnoreRule compiles a single pattern line. Blank lines and comments
+// yield ok == false.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var r ignoreRule
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return r, false
+	}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return r, false
+	}
+	r.segs = strings.Split(line, "/")
+	return r, true
+}
+
+// match reports whether the rule matches rel, a slash-separated path relative
+// to the rule's base directory.
+func (r ignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	if !r.anchored {
+		ok, _ := path.Match(r.segs[0], parts[len(parts)-1])
+		return ok
+	}
+	return matchSegments(r.segs, parts)
+}
+
+// matchParent reports whether the rule matches a directory above rel.
+func (r ignoreRule) matchParent(rel string) bool {
+	for i := strings.LastIndexByte(rel, '/'); i > 0; i = strings.LastIndexByte(rel, '/') {
+		rel = rel[:i]
+		if r.match(rel, true) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches pattern segments against path segments, letting
+// "**" consume zero or more whole segments. A trailing "**" needs at least
+// one, so "dir/**" matches what is inside dir but not dir itself, and a
+// later "!dir/keep" can still re-include a child.
+func matchSegments(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			if len(rest) == 0 {
+				return len(parts) > 0
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// ignoreList is an ordered set of rules relative to a base directory.
+type ignoreList struct {
+	base  string
+	rules []ignoreRule
+}
+
+// apply runs the rules over p in order, so the last matching rule wins. A
+// rule that matches a directory above p counts as matching p, which lets
+// "!dir/keep" re-include the tree under dir/keep after "dir/**".
+// excluded carries the verdict of lower-precedence lists.
+func (l ignoreList) apply(p string, isDir, excluded bool) bool {
+	if len(l.rules) == 0 {
+		return excluded
+	}
+	rel, err := filepath.Rel(l.base, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return excluded
+	}
+	rel = filepath.ToSlash(rel)
+	for _, r := range l.rules {
+		if r.match(rel, isDir) || r.matchParent(rel) {
+			excluded = !r.negate
+		}
+	}
+	return excluded
+}
+
+// readIgnoreFile loads the rules of an ignore file; a missing file yields nil.
+func readIgnoreFile(name string) []ignoreRule {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var rules []ignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if r, ok := parseIgnoreRule(sc.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// excludeRules compiles Options.Excludes relative to the scan root. For
+// compatibility with the old matcher, absolute patterns under root are made
+// root-anchored and a leading "*/" means "any directory" ("**/").
+func excludeRules(root string, patterns []string) []ignoreRule {
+	absRoot, _ := filepath.Abs(root)
+	var rules []ignoreRule
+	for _, pat := range patterns {
+		neg := strings.HasPrefix(pat, "!")
+		pat = strings.TrimPrefix(pat, "!")
+		if filepath.IsAbs(pat) {
+			if rel, err := filepath.Rel(absRoot, pat); err == nil && !strings.HasPrefix(rel, "..") {
+				pat = "/" + filepath.ToSlash(rel)
+			}
+		}
+		pat = filepath.ToSlash(pat)
+		if strings.HasPrefix(pat, "*/") {
+			pat = "**/" + pat[2:]
+		}
+		if neg {
+			pat = "!" + pat
+		}
+		if r, ok := parseIgnoreRule(pat); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// ignoreSet decides exclusion during a discovery walk. Options.Excludes has
+// the lowest precedence; .nmmignore files override it, deeper files
+// overriding shallower ones, as with .gitignore.
+type ignoreSet struct {
+	root     string
+	excludes ignoreList
+	useFiles bool
+
+	mu    sync.RWMutex
+	files map[string]ignoreList // directory -> rules of its .nmmignore
+}
+
+func newIgnoreSet(root string, opts Options) *ignoreSet {
+	return &ignoreSet{
+		root:     filepath.Clean(root),
+		excludes: ignoreList{base: root, rules: excludeRules(root, opts.Excludes)},
+		useFiles: opts.IgnoreFiles,
+		files:    map[string]ignoreList{},
+	}
+}
+
+// enter loads the ignore file of a directory the walk is about to descend into.
+func (s *ignoreSet) enter(dir string) {
+	if !s.useFiles {
+		return
+	}
+	if rules := readIgnoreFile(filepath.Join(dir, ignoreFileName)); len(rules) > 0 {
+		s.mu.Lock()
+		s.files[filepath.Clean(dir)] = ignoreList{base: dir, rules: rules}
+		s.mu.Unlock()
+	}
+}
+
+// excluded reports whether p should be skipped. The scan root never is.
+func (s *ignoreSet) excluded(p string, isDir bool) bool {
+	ex := s.excludes.apply(p, isDir, false)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.files) == 0 {
+		return ex
+	}
+	rel, err := filepath.Rel(s.root, p)
+	if err != nil || rel == "." {
+		return ex
+	}
+	dir := s.root
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i := 0; i < len(parts); i++ {
+		if l, ok := s.files[dir]; ok {
+			ex = l.apply(p, isDir, ex)
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+	return ex
+}
@@ -0,0 +1,55 @@
+package scanner
+
+import "testing"
+
+func TestIgnoreRule_Match(t *testing.T) {
+	cases := []struct {
+		pat   string
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"node_modules", "a/b/node_modules", true, true},
+		{"fixtures/", "test/fixtures", true, true},
+		{"fixtures/", "test/fixtures", false, false},
+		{"/a", "a", true, true},
+		{"/a", "x/a", true, false},
+		{"a/*", "a/node_modules", true, true},
+		{"a/*", "x/a/node_modules", true, false},
+		{"**/fixtures/**", "pkg/test/fixtures/app/node_modules", true, true},
+		{"**/fixtures/**", "pkg/fixtures-old/node_modules", true, false},
+		{"**/fixtures/**", "pkg/fixtures", true, false},
+		{"packages/**/node_modules", "packages/node_modules", true, true},
+		{"packages/**/node_modules", "packages/a/b/node_modules", true, true},
+		{"!keep", "keep", true, true},
+		{"*.tmp", "x/y.tmp", false, true},
+	}
+	for _, c := range cases {
+		r, ok := parseIgnoreRule(c.pat)
+		if !ok {
+			t.Fatalf("parseIgnoreRule(%q) rejected", c.pat)
+		}
+		if got := r.match(c.rel, c.isDir); got != c.want {
+			t.Fatalf("%q match %q (dir=%v) = %v; want %v", c.pat, c.rel, c.isDir, got, c.want)
+		}
+	}
+	for _, skip := range []string{"", "   ", "# comment", "/"} {
+		if _, ok := parseIgnoreRule(skip); ok {
+			t.Fatalf("parseIgnoreRule(%q) should yield no rule", skip)
+		}
+	}
+}
+
+func TestIgnoreList_Negation(t *testing.T) {
+	l := ignoreList{base: "/r", rules: excludeRules("/r", []string{"**/fixtures/**", "!**/fixtures/keep"})}
+	if !l.apply("/r/t/fixtures/app", true, false) {
+		t.Fatalf("expected fixtures/app excluded")
+	}
+	if l.apply("/r/t/fixtures/keep", true, false) {
+		t.Fatalf("expected fixtures/keep re-included")
+	}
+	// the old substring fallback made "*/a/*" match any path containing "a"
+	if l2 := (ignoreList{base: "/r", rules: excludeRules("/r", []string{"*/a/*"})}); l2.apply("/r/data/node_modules", true, false) {
+		t.Fatalf("*/a/* must not match data/node_modules")
+	}
+}
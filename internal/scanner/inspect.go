@@ -0,0 +1,136 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Package describes one top-level entry of a node_modules directory.
+type Package struct {
+	Name        string // "react" or "@babel/core"; dot dirs such as ".pnpm" keep their name
+	Version     string // from the package's package.json; empty when unknown
+	Path        string
+	Size        int64 // apparent bytes, nested node_modules included
+	Reclaimable int64
+	Files       int64
+	Nested      bool // has its own node_modules to descend into
+	Symlink     bool // linked in (pnpm, workspaces); sized through only with FollowSymlink
+}
+
+// Inspect lists the packages directly inside the node_modules directory dir,
+// scoped @org/* packages included, largest first. To drill down, inspect
+// filepath.Join(p.Path, "node_modules") of a package with Nested set.
+// Packages that could not be measured are listed with what was counted and
+// their errors returned; when dir cannot be read or ctx is cancelled the
+// packages are nil.
+func Inspect(ctx context.Context, dir string, opts Options) ([]Package, []*ScanError) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	opts = opts.withDefaults()
+	pkgs, err := listPackages(dir)
+	if err != nil {
+		return nil, []*ScanError{newScanError(OpWalk, dir, err)}
+	}
+
+	claims := newInodeClaims()
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []*ScanError
+	worker := func() {
+		defer wg.Done()
+		for i := range jobs {
+			p := &pkgs[i]
+			u, err := dirSize(ctx, p.Path, opts.FollowSymlink, claims, budget{})
+			p.Size, p.Reclaimable, p.Files = u.apparent, u.reclaimable, u.files
+			if err != nil && ctx.Err() == nil {
+				mu.Lock()
+				errs = append(errs, newScanError(OpSize, p.Path, err))
+				mu.Unlock()
+			}
+		}
+	}
+	wg.Add(opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		go worker()
+	}
+	for i := range pkgs {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, []*ScanError{newScanError(OpSize, dir, err)}
+	}
+
+	sort.Slice(pkgs, func(i, j int) bool {
+		if pkgs[i].Size == pkgs[j].Size {
+			return pkgs[i].Name < pkgs[j].Name
+		}
+		return pkgs[i].Size > pkgs[j].Size
+	})
+	return pkgs, errs
+}
+
+// listPackages reads the entries of a node_modules directory, expanding
+// @scope directories. Plain files such as .package-lock.json are skipped.
+func listPackages(dir string) ([]Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []Package
+	for _, e := range entries {
+		name := e.Name()
+		path := filepath.Join(dir, name)
+		if strings.HasPrefix(name, "@") && e.IsDir() {
+			scoped, err := os.ReadDir(path)
+			if err != nil {
+				continue
+			}
+			for _, se := range scoped {
+				if p, ok := newPackage(name+"/"+se.Name(), filepath.Join(path, se.Name()), se.Type()); ok {
+					pkgs = append(pkgs, p)
+				}
+			}
+			continue
+		}
+		if p, ok := newPackage(name, path, e.Type()); ok {
+			pkgs = append(pkgs, p)
+		}
+	}
+	return pkgs, nil
+}
+
+// newPackage builds the unsized entry for a package directory or a symlink
+// to one.
+func newPackage(name, path string, mode os.FileMode) (Package, bool) {
+	p := Package{Name: name, Path: path, Symlink: mode&os.ModeSymlink != 0}
+	if !mode.IsDir() && !p.Symlink {
+		return p, false
+	}
+	if p.Symlink {
+		if st, err := os.Stat(path); err != nil || !st.IsDir() {
+			return p, false
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(path, "package.json")); err == nil {
+		var pj packageJSON
+		if json.Unmarshal(data, &pj) == nil {
+			p.Version = pj.Version
+		}
+	}
+	if st, err := os.Stat(filepath.Join(path, "node_modules")); err == nil && st.IsDir() {
+		p.Nested = true
+	}
+	return p, true
+}
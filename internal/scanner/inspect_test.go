@@ -0,0 +1,61 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInspect_ListsPackagesBySize(t *testing.T) {
+	nm := filepath.Join(t.TempDir(), "node_modules")
+	pkg := func(dir, manifest string, size int64) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if manifest != "" {
+			if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(manifest), 0o644); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+		}
+		writeFileOfSize(t, filepath.Join(dir, "index.js"), size)
+	}
+	pkg(filepath.Join(nm, "small"), `{"name":"small","version":"1.0.0"}`, 10)
+	pkg(filepath.Join(nm, "@org", "big"), `{"name":"@org/big","version":"2.1.0"}`, 1000)
+	pkg(filepath.Join(nm, "@org", "big", "node_modules", "dep"), `{"name":"dep","version":"0.1.0"}`, 500)
+	pkg(filepath.Join(nm, ".cache"), "", 200)
+	if err := os.WriteFile(filepath.Join(nm, ".package-lock.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	pkgs, errs := Inspect(nil, nm, Options{})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(pkgs) != 3 {
+		t.Fatalf("expected 3 packages, got %+v", pkgs)
+	}
+	big := pkgs[0]
+	if big.Name != "@org/big" || big.Version != "2.1.0" || !big.Nested {
+		t.Fatalf("unexpected first package: %+v", big)
+	}
+	manifestSize := int64(len(`{"name":"@org/big","version":"2.1.0"}`) + len(`{"name":"dep","version":"0.1.0"}`))
+	if big.Size != 1500+manifestSize || big.Files != 4 {
+		t.Fatalf("@org/big: size %d files %d", big.Size, big.Files)
+	}
+	if pkgs[1].Name != ".cache" || pkgs[2].Name != "small" || pkgs[2].Version != "1.0.0" {
+		t.Fatalf("unexpected order: %s, %s", pkgs[1].Name, pkgs[2].Name)
+	}
+
+	nested, errs := Inspect(nil, filepath.Join(big.Path, "node_modules"), Options{})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(nested) != 1 || nested[0].Name != "dep" || nested[0].Nested {
+		t.Fatalf("unexpected nested packages: %+v", nested)
+	}
+
+	missing, errs := Inspect(nil, filepath.Join(filepath.Dir(nm), "missing"), Options{})
+	if missing != nil || len(errs) != 1 || errs[0].Kind != ErrNotExist || errs[0].Op != OpWalk {
+		t.Fatalf("missing dir: packages %+v, errors %v", missing, errs)
+	}
+}
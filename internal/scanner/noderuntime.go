@@ -0,0 +1,481 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NodeManager is a Node version manager that installs runtimes in a
+// directory of its own.
+type NodeManager string
+
+const (
+	ManagerNVM   NodeManager = "nvm"   // $NVM_DIR/versions/node/v<version>
+	ManagerFNM   NodeManager = "fnm"   // $FNM_DIR/node-versions/v<version>
+	ManagerVolta NodeManager = "volta" // $VOLTA_HOME/tools/image/node/<version>
+)
+
+// NodeManagers lists the version managers in display order.
+var NodeManagers = []NodeManager{ManagerNVM, ManagerFNM, ManagerVolta}
+
+// NodeRuntime is one Node version installed by a version manager.
+type NodeRuntime struct {
+	Manager     NodeManager
+	Version     string // without the leading "v"
+	Path        string
+	Size        int64
+	Reclaimable int64
+	Files       int64
+
+	// Globals are the globally installed packages as name@version, without
+	// the npm and corepack every runtime ships with.
+	Globals []string
+
+	// Default marks the manager's default version. It is never offered for
+	// deletion, whether a project requests it or not.
+	Default bool
+
+	Status ToolStatus
+	UsedBy []string // project dirs whose .nvmrc, .node-version or engines resolve to this version
+	Err    *ScanError
+}
+
+// nodeManagerDir is where manager keeps its runtimes, honouring the
+// manager's environment override; "" when it cannot be worked out.
+func nodeManagerDir(manager NodeManager) string {
+	env := map[NodeManager]string{ManagerNVM: "NVM_DIR", ManagerFNM: "FNM_DIR", ManagerVolta: "VOLTA_HOME"}[manager]
+	if dir := os.Getenv(env); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	switch manager {
+	case ManagerNVM:
+		return filepath.Join(home, ".nvm")
+	case ManagerVolta:
+		return filepath.Join(home, ".volta")
+	}
+	// fnm keeps using ~/.fnm when an older release created it
+	if st, err := os.Stat(filepath.Join(home, ".fnm")); err == nil && st.IsDir() {
+		return filepath.Join(home, ".fnm")
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "fnm")
+	case "windows":
+		if dir := os.Getenv("APPDATA"); dir != "" {
+			return filepath.Join(dir, "fnm")
+		}
+		return ""
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "fnm")
+	}
+	return filepath.Join(home, ".local", "share", "fnm")
+}
+
+// listNodeRuntimes lists the runtimes manager installed below dir, unsized,
+// with their global packages and the default marked.
+func listNodeRuntimes(manager NodeManager, dir string) []NodeRuntime {
+	var versionsDir, prefix string
+	switch manager {
+	case ManagerNVM:
+		versionsDir, prefix = filepath.Join(dir, "versions", "node"), "v"
+	case ManagerFNM:
+		versionsDir, prefix = filepath.Join(dir, "node-versions"), "v"
+	default:
+		versionsDir = filepath.Join(dir, "tools", "image", "node")
+	}
+	var out []NodeRuntime
+	entries, _ := os.ReadDir(versionsDir)
+	for _, e := range entries {
+		version := strings.TrimPrefix(e.Name(), prefix)
+		if _, n, ok := parseSemver(version); !e.IsDir() || !ok || n != 3 {
+			continue
+		}
+		rt := NodeRuntime{Manager: manager, Version: version, Path: filepath.Join(versionsDir, e.Name())}
+		install := rt.Path
+		if manager == ManagerFNM {
+			install = filepath.Join(install, "installation")
+		}
+		rt.Globals = globalPackages(install)
+		out = append(out, rt)
+	}
+	def := defaultNodeVersion(manager, dir, out)
+	for i := range out {
+		out[i].Default = out[i].Version == def
+	}
+	return out
+}
+
+// globalPackages lists the packages installed globally into the runtime at
+// install: lib/node_modules, or node_modules on Windows.
+func globalPackages(install string) []string {
+	var out []string
+	for _, nm := range []string{filepath.Join(install, "lib", "node_modules"), filepath.Join(install, "node_modules")} {
+		entries, _ := os.ReadDir(nm)
+		for _, e := range entries {
+			names := []string{e.Name()}
+			if strings.HasPrefix(e.Name(), "@") {
+				names = nil
+				scoped, _ := os.ReadDir(filepath.Join(nm, e.Name()))
+				for _, s := range scoped {
+					names = append(names, e.Name()+"/"+s.Name())
+				}
+			}
+			for _, name := range names {
+				if name == "npm" || name == "corepack" || strings.HasPrefix(name, ".") {
+					continue
+				}
+				if v := readPackageVersion(filepath.Join(nm, name)); v != "" {
+					out = append(out, name+"@"+v)
+				} else {
+					out = append(out, name)
+				}
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// defaultNodeVersion returns the version of runtimes the manager runs
+// outside any project: nvm's "default" alias, fnm's "default" alias link or
+// volta's platform.json. It is "" when none is set or it cannot be resolved.
+func defaultNodeVersion(manager NodeManager, dir string, runtimes []NodeRuntime) string {
+	switch manager {
+	case ManagerNVM:
+		data, err := os.ReadFile(filepath.Join(dir, "alias", "default"))
+		if err != nil {
+			return ""
+		}
+		if rt := resolveNodeRequest(strings.TrimSpace(string(data)), runtimes); rt != nil {
+			return rt.Version
+		}
+	case ManagerFNM:
+		target, err := filepath.EvalSymlinks(filepath.Join(dir, "aliases", "default"))
+		if err != nil {
+			return ""
+		}
+		for _, rt := range runtimes {
+			if resolved, err := filepath.EvalSymlinks(rt.Path); err == nil && strings.HasPrefix(target+string(filepath.Separator), resolved+string(filepath.Separator)) {
+				return rt.Version
+			}
+		}
+	case ManagerVolta:
+		data, err := os.ReadFile(filepath.Join(dir, "tools", "user", "platform.json"))
+		if err != nil {
+			return ""
+		}
+		var platform struct {
+			Node struct {
+				Runtime string `json:"runtime"`
+			} `json:"node"`
+		}
+		_ = json.Unmarshal(data, &platform)
+		return platform.Node.Runtime
+	}
+	return ""
+}
+
+// nodeRequest reads the Node version a project asks for: the nearest
+// .nvmrc or .node-version in its directory or a parent, else the volta pin
+// or the engines field of its package.json. It returns "" when the project
+// asks for none.
+func nodeRequest(p ProjectInfo) string {
+	for dir := p.Dir; ; {
+		for _, name := range []string{".nvmrc", ".node-version"} {
+			if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+				// the first line holds the version; nvm allows comments after it
+				line, _, _ := strings.Cut(string(data), "\n")
+				line, _, _ = strings.Cut(line, "#")
+				if line = strings.TrimSpace(line); line != "" {
+					return line
+				}
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	data, err := os.ReadFile(filepath.Join(p.Dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var pj struct {
+		Volta struct {
+			Node string `json:"node"`
+		} `json:"volta"`
+		Engines struct {
+			Node string `json:"node"`
+		} `json:"engines"`
+	}
+	_ = json.Unmarshal(data, &pj)
+	if v := strings.TrimSpace(pj.Volta.Node); v != "" {
+		return v
+	}
+	return strings.TrimSpace(pj.Engines.Node)
+}
+
+// ltsCodenames maps the codenames nvm accepts as lts/<name> to their major.
+var ltsCodenames = map[string]int{
+	"argon": 4, "boron": 6, "carbon": 8, "dubnium": 10, "erbium": 12, "fermium": 14,
+	"gallium": 16, "hydrogen": 18, "iron": 20, "jod": 22, "krypton": 24,
+}
+
+// nodeSpecMatcher turns a version request into a test on installed
+// versions: a version or prefix ("18", "v18.17.0"), an alias ("node",
+// "lts/*", "lts/hydrogen") or a semver range as found in engines. ok is
+// false for requests it cannot make sense of.
+func nodeSpecMatcher(spec string) (match func(semver) bool, ok bool) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	switch {
+	case spec == "node" || spec == "stable" || spec == "latest" || spec == "current":
+		return func(semver) bool { return true }, true
+	case spec == "lts/*" || spec == "lts":
+		// every even major becomes an LTS line
+		return func(v semver) bool { return v[0]%2 == 0 }, true
+	case strings.HasPrefix(spec, "lts/"):
+		major, known := ltsCodenames[strings.TrimPrefix(spec, "lts/")]
+		return func(v semver) bool { return v[0] == major }, known
+	}
+	return semverRange(spec)
+}
+
+// resolveNodeRequest picks the runtime a version manager would run for spec:
+// the newest installed version it matches, or nil.
+func resolveNodeRequest(spec string, runtimes []NodeRuntime) *NodeRuntime {
+	match, ok := nodeSpecMatcher(spec)
+	if !ok {
+		return nil
+	}
+	var best *NodeRuntime
+	var bestV semver
+	for i := range runtimes {
+		v, _, _ := parseSemver(runtimes[i].Version)
+		if match(v) && (best == nil || bestV.less(v)) {
+			best, bestV = &runtimes[i], v
+		}
+	}
+	return best
+}
+
+// scanNodeRuntimes lists the runtimes of every version manager and marks
+// those a project requests as used. A request that cannot be understood
+// marks every version not requested otherwise as unknown.
+func scanNodeRuntimes(projects []ProjectInfo) []NodeRuntime {
+	var out []NodeRuntime
+	for _, manager := range NodeManagers {
+		if dir := nodeManagerDir(manager); dir != "" {
+			out = append(out, listNodeRuntimes(manager, dir)...)
+		}
+	}
+	if len(out) == 0 {
+		return out
+	}
+	usedBy := map[string][]string{}
+	unknown := false
+	for _, p := range projects {
+		spec := nodeRequest(p)
+		if spec == "" || spec == "system" {
+			continue
+		}
+		if _, ok := nodeSpecMatcher(spec); !ok {
+			unknown = true
+			continue
+		}
+		// each manager resolves the request among its own runtimes
+		for _, manager := range NodeManagers {
+			var own []NodeRuntime
+			for _, rt := range out {
+				if rt.Manager == manager {
+					own = append(own, rt)
+				}
+			}
+			if rt := resolveNodeRequest(spec, own); rt != nil {
+				usedBy[rt.Path] = append(usedBy[rt.Path], p.Dir)
+			}
+		}
+	}
+	for i := range out {
+		rt := &out[i]
+		rt.Status = ToolUnused
+		if users := usedBy[rt.Path]; len(users) > 0 {
+			rt.Status = ToolUsed
+			rt.UsedBy = dedupeSorted(users)
+		} else if unknown {
+			rt.Status = ToolUnknown
+		}
+	}
+	return out
+}
+
+// measure sizes rt like a cache.
+func (rt *NodeRuntime) measure(ctx context.Context, claims *inodeClaims, opts Options) {
+	u, err := dirSize(ctx, rt.Path, false, claims, opts.budget())
+	rt.Size, rt.Reclaimable, rt.Files = u.apparent, u.reclaimable, u.files
+	if err != nil {
+		rt.Err = newScanError(OpSize, rt.Path, err)
+	}
+}
+
+// PlanRuntimePrune plans the removal of the Node runtimes no scanned
+// project requests, leaving each manager's default alone. runtimes and
+// projects normally come from ScanCaches; with no projects it returns
+// ErrNoReferences.
+func PlanRuntimePrune(runtimes []NodeRuntime, projects []ProjectInfo) (PrunePlan, error) {
+	plan := PrunePlan{Action: "remove Node versions no scanned project requests"}
+	if len(projects) == 0 {
+		return plan, ErrNoReferences
+	}
+	for _, rt := range runtimes {
+		if rt.Status != ToolUnused || rt.Default {
+			plan.Kept++
+			continue
+		}
+		plan.Entries = append(plan.Entries, PruneEntry{Path: rt.Path, Size: rt.Size, Files: rt.Files})
+		plan.Size += rt.Size
+	}
+	return plan, nil
+}
+
+// semver is a major.minor.patch version; pre-release tags are ignored.
+type semver [3]int
+
+func (v semver) less(w semver) bool {
+	for i := range v {
+		if v[i] != w[i] {
+			return v[i] < w[i]
+		}
+	}
+	return false
+}
+
+// parseSemver parses a full or partial version such as "v18", "18.x" or
+// "18.17.0-rc.1". n counts the parts given; "x" and "*" end the version.
+func parseSemver(s string) (v semver, n int, ok bool) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "="), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" || s == "x" || s == "X" || s == "*" {
+		return v, 0, s != ""
+	}
+	for _, part := range strings.Split(s, ".") {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		if n == 3 {
+			return v, n, false
+		}
+		num, err := strconv.Atoi(part)
+		if err != nil || num < 0 {
+			return v, n, false
+		}
+		v[n] = num
+		n++
+	}
+	return v, n, true
+}
+
+// bump returns the smallest version above every version matching the first
+// n parts of v.
+func (v semver) bump(n int) semver {
+	var w semver
+	copy(w[:n], v[:n])
+	if n > 0 {
+		w[n-1]++
+	}
+	return w
+}
+
+var rangeOpSpaceRe = regexp.MustCompile(`([<>=~^]+)\s+`)
+
+// semverRange parses a semver range as used by engines: comparators
+// (>=, >, <=, <, =, ~, ^) joined by spaces, hyphen ranges and x-ranges,
+// with alternatives separated by "||".
+func semverRange(rng string) (func(semver) bool, bool) {
+	type bound struct {
+		lo, hi       semver
+		hasLo, hasHi bool
+		loIncl       bool
+	}
+	var alts [][]bound
+	for _, alt := range strings.Split(rng, "||") {
+		alt = strings.TrimSpace(rangeOpSpaceRe.ReplaceAllString(alt, "$1"))
+		var bounds []bound
+		if lo, hi, found := strings.Cut(alt, " - "); found {
+			a, _, ok1 := parseSemver(lo)
+			b, n, ok2 := parseSemver(hi)
+			if !ok1 || !ok2 {
+				return nil, false
+			}
+			alts = append(alts, []bound{{lo: a, hasLo: true, loIncl: true, hi: b.bump(n), hasHi: n > 0}})
+			continue
+		}
+		for _, c := range strings.Fields(alt) {
+			op := c[:len(c)-len(strings.TrimLeft(c, "<>=~^"))]
+			v, n, ok := parseSemver(c[len(op):])
+			if !ok {
+				return nil, false
+			}
+			var b bound
+			switch op {
+			case ">=":
+				b = bound{lo: v, hasLo: true, loIncl: true}
+			case ">":
+				b = bound{lo: v, hasLo: true}
+				if n < 3 {
+					b = bound{lo: v.bump(n), hasLo: n > 0, loIncl: true}
+				}
+			case "<":
+				b = bound{hi: v, hasHi: true}
+			case "<=":
+				b = bound{hi: v.bump(n), hasHi: n > 0}
+			case "~":
+				up := n
+				if up > 2 {
+					up = 2
+				}
+				b = bound{lo: v, hasLo: true, loIncl: true, hi: v.bump(up), hasHi: n > 0}
+			case "^":
+				// Node majors are never 0, so a caret keeps the major
+				b = bound{lo: v, hasLo: true, loIncl: true, hi: v.bump(1), hasHi: n > 0}
+			case "", "=":
+				b = bound{lo: v, hasLo: true, loIncl: true, hi: v.bump(n), hasHi: n > 0}
+			default:
+				return nil, false
+			}
+			bounds = append(bounds, b)
+		}
+		alts = append(alts, bounds)
+	}
+	return func(v semver) bool {
+		for _, bounds := range alts {
+			ok := true
+			for _, b := range bounds {
+				if b.hasLo && (v.less(b.lo) || (!b.loIncl && v == b.lo)) {
+					ok = false
+				}
+				if b.hasHi && !v.less(b.hi) {
+					ok = false
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+		return false
+	}, true
+}
@@ -0,0 +1,160 @@
+package scanner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestScanCaches_NodeRuntimes(t *testing.T) {
+	home := isolateCaches(t)
+	writeFiles(t, home, map[string]string{
+		".nvm/versions/node/v18.17.0/bin/node":                                 "18",
+		".nvm/versions/node/v18.20.4/bin/node":                                 "18",
+		".nvm/versions/node/v18.20.4/lib/node_modules/npm/package.json":        `{"name": "npm", "version": "10.7.0"}`,
+		".nvm/versions/node/v18.20.4/lib/node_modules/typescript/package.json": `{"name": "typescript", "version": "5.4.5"}`,
+		".nvm/versions/node/v18.20.4/lib/node_modules/@vue/cli/package.json":   `{"name": "@vue/cli", "version": "5.0.8"}`,
+		".nvm/versions/node/v20.11.1/bin/node":                                 "20",
+		".nvm/versions/node/v16.20.2/bin/node":                                 "16",
+		".nvm/alias/default":                                                   "16\n",
+		".local/share/fnm/node-versions/v20.11.1/installation/bin/node":        "20",
+		".local/share/fnm/node-versions/v21.7.0/installation/bin/node":         "21",
+		".local/share/fnm/node-versions/.downloads/x":                          "",
+		".volta/tools/image/node/22.3.0/bin/node":                              "22",
+		".volta/tools/image/node/20.11.1/bin/node":                             "20",
+		".volta/tools/user/platform.json":                                      `{"node": {"runtime": "22.3.0", "npm": null}}`,
+	})
+	fnm := filepath.Join(home, ".local", "share", "fnm")
+	if err := os.MkdirAll(filepath.Join(fnm, "aliases"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(fnm, "node-versions", "v21.7.0", "installation"), filepath.Join(fnm, "aliases", "default")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		// newest installed 18.x
+		"a/.nvmrc":                  "v18 # matches the CI image\n",
+		"a/package.json":            `{"name": "a", "engines": {"node": ">=16"}}`,
+		"a/node_modules/x/index.js": "",
+		"b/.node-version":           "lts/iron",
+		"b/node_modules/x/index.js": "",
+		"c/package.json":            `{"name": "c", "engines": {"node": "^20.0.0 || >=22 <23"}}`,
+		"c/node_modules/x/index.js": "",
+	})
+
+	rep, errs := ScanCaches(nil, root, Options{})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	var got []string
+	for _, rt := range rep.Runtimes {
+		line := string(rt.Manager) + " " + rt.Version + " " + string(rt.Status)
+		if rt.Default {
+			line += " default"
+		}
+		got = append(got, line)
+		if rt.Size == 0 || rt.Err != nil {
+			t.Errorf("%s: not measured: %+v", rt.Path, rt)
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		"fnm 20.11.1 used",
+		"fnm 21.7.0 unused default",
+		"nvm 16.20.2 unused default",
+		"nvm 18.17.0 unused",
+		"nvm 18.20.4 used",
+		"nvm 20.11.1 used",
+		"volta 20.11.1 used",
+		"volta 22.3.0 used default",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("runtimes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for _, rt := range rep.Runtimes {
+		if rt.Manager == ManagerNVM && rt.Version == "18.20.4" {
+			if strings.Join(rt.Globals, " ") != "@vue/cli@5.0.8 typescript@5.4.5" {
+				t.Errorf("globals: %v", rt.Globals)
+			}
+			if len(rt.UsedBy) != 1 || filepath.Base(rt.UsedBy[0]) != "a" {
+				t.Errorf("used by %v, want a", rt.UsedBy)
+			}
+		}
+	}
+
+	plan, err := PlanRuntimePrune(rep.Runtimes, rep.Projects)
+	if err != nil || len(plan.Entries) != 1 || plan.Kept != 7 || filepath.Base(plan.Entries[0].Path) != "v18.17.0" {
+		t.Fatalf("expected only v18.17.0 pruned, got %+v (%v)", plan, err)
+	}
+	if _, err := PlanRuntimePrune(rep.Runtimes, nil); !errors.Is(err, ErrNoReferences) {
+		t.Fatalf("expected ErrNoReferences without projects, got %v", err)
+	}
+}
+
+func TestNodeRequest(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"volta/package.json":   `{"name": "volta", "volta": {"node": "20.11.1"}, "engines": {"node": ">=16"}}`,
+		"engines/package.json": `{"name": "engines", "engines": {"node": ">=16"}}`,
+		"nvm/.nvmrc":           "18\n",
+		"nvm/package.json":     `{"name": "nvm", "volta": {"node": "20.11.1"}}`,
+		"none/package.json":    `{"name": "none"}`,
+	})
+	for dir, want := range map[string]string{
+		"volta":   "20.11.1",
+		"engines": ">=16",
+		"nvm":     "18",
+		"none":    "",
+	} {
+		if got := nodeRequest(readProject(filepath.Join(root, dir))); got != want {
+			t.Errorf("%s: request %q, want %q", dir, got, want)
+		}
+	}
+}
+
+func TestNodeSpecMatcher(t *testing.T) {
+	cases := []struct {
+		spec    string
+		match   []string
+		nomatch []string
+	}{
+		{"18", []string{"18.0.0", "18.20.4"}, []string{"17.9.1", "19.0.0"}},
+		{"v18.17", []string{"18.17.0", "18.17.1"}, []string{"18.18.0"}},
+		{"lts/*", []string{"20.11.1"}, []string{"21.7.0"}},
+		{"lts/hydrogen", []string{"18.1.0"}, []string{"20.0.0"}},
+		{">=16.14", []string{"16.14.0", "22.0.0"}, []string{"16.13.2"}},
+		{">= 16 < 20", []string{"18.0.0"}, []string{"20.0.0", "15.9.0"}},
+		{"^18.12.0", []string{"18.12.0", "18.20.0"}, []string{"18.11.0", "19.0.0"}},
+		{"~18.12", []string{"18.12.5"}, []string{"18.13.0"}},
+		{"16.x || 18.x", []string{"16.1.0", "18.2.0"}, []string{"17.0.0"}},
+		{"14 - 16", []string{"14.0.0", "16.20.2"}, []string{"17.0.0"}},
+		{">18", []string{"19.0.0"}, []string{"18.20.0"}},
+		{"<=18", []string{"18.20.0"}, []string{"19.0.0"}},
+	}
+	for _, c := range cases {
+		match, ok := nodeSpecMatcher(c.spec)
+		if !ok {
+			t.Errorf("%q: not understood", c.spec)
+			continue
+		}
+		for _, v := range c.match {
+			if sv, _, _ := parseSemver(v); !match(sv) {
+				t.Errorf("%q should match %s", c.spec, v)
+			}
+		}
+		for _, v := range c.nomatch {
+			if sv, _, _ := parseSemver(v); match(sv) {
+				t.Errorf("%q should not match %s", c.spec, v)
+			}
+		}
+	}
+	for _, spec := range []string{"iojs", "lts/unknown", "18.a"} {
+		if _, ok := nodeSpecMatcher(spec); ok {
+			t.Errorf("%q should not be understood", spec)
+		}
+	}
+}
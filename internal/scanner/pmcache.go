@@ -0,0 +1,150 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// npmCacheDir is npm's content-addressable cache: $npm_config_cache/_cacache,
+// ~/.npm/_cacache by default.
+func npmCacheDir() string {
+	if dir := os.Getenv("npm_config_cache"); dir != "" {
+		return filepath.Join(dir, "_cacache")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".npm", "_cacache")
+}
+
+// pnpmStoreRoots lists where the pnpm content-addressable store may live.
+func pnpmStoreRoots() []string {
+	var roots []string
+	if dir := os.Getenv("PNPM_STORE_DIR"); dir != "" {
+		roots = append(roots, dir)
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		roots = append(roots, filepath.Join(dir, "pnpm", "store"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		switch runtime.GOOS {
+		case "darwin":
+			roots = append(roots, filepath.Join(home, "Library", "pnpm", "store"))
+		case "windows":
+			if dir := os.Getenv("LOCALAPPDATA"); dir != "" {
+				roots = append(roots, filepath.Join(dir, "pnpm", "store"))
+			}
+		default:
+			roots = append(roots, filepath.Join(home, ".local", "share", "pnpm", "store"))
+		}
+	}
+	return roots
+}
+
+// pnpmStoreDirs lists the v3 layout (pnpm 7 to 9) of each pnpm store root.
+// pnpm 10 moved the package indexes out of files/ into v10/index/, which is
+// not read, so its packages count as not cached.
+func pnpmStoreDirs() []string {
+	var dirs []string
+	for _, r := range pnpmStoreRoots() {
+		dirs = append(dirs, filepath.Join(r, "v3"))
+	}
+	return dirs
+}
+
+// yarnCacheDirs lists yarn's caches: yarn classic's global cache (its
+// versioned v6 subdirectory is resolved by the caller), yarn berry's global
+// mirror and, when projectDir is set, the project's own .yarn/cache.
+func yarnCacheDirs(projectDir string) []string {
+	dirs := yarnClassicCacheDirs()
+	if dir := yarnBerryCacheDir(); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if projectDir != "" {
+		dirs = append(dirs, filepath.Join(projectDir, ".yarn", "cache"))
+	}
+	return dirs
+}
+
+// yarnClassicCacheDirs lists where yarn classic's global cache may live.
+func yarnClassicCacheDirs() []string {
+	var dirs []string
+	if dir := os.Getenv("YARN_CACHE_FOLDER"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		if runtime.GOOS == "darwin" {
+			dirs = append(dirs, filepath.Join(dir, "Yarn"))
+		} else {
+			dirs = append(dirs, filepath.Join(dir, "yarn"))
+		}
+	}
+	return dirs
+}
+
+// yarnBerryCacheDir is yarn 2+'s global mirror.
+func yarnBerryCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".yarn", "berry", "cache")
+}
+
+// bunCacheDir is bun's global install cache.
+func bunCacheDir() string {
+	if dir := os.Getenv("BUN_INSTALL_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".bun", "install", "cache")
+}
+
+// isVersionDir matches cache layout directories such as v3, v6 or v10.
+func isVersionDir(name string) bool {
+	return len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == ""
+}
+
+// dirListings remembers sorted directory listings for prefix lookups, so
+// that a yarn cache with tens of thousands of entries is read once per scan.
+type dirListings struct {
+	mu    sync.Mutex
+	names map[string][]string
+}
+
+func newDirListings() *dirListings {
+	return &dirListings{names: make(map[string][]string)}
+}
+
+func (l *dirListings) list(dir string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if names, ok := l.names[dir]; ok {
+		return names
+	}
+	var names []string
+	if entries, err := os.ReadDir(dir); err == nil {
+		names = make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		sort.Strings(names)
+	}
+	l.names[dir] = names
+	return names
+}
+
+// hasPrefix reports whether dir has an entry starting with prefix.
+func (l *dirListings) hasPrefix(dir, prefix string) bool {
+	names := l.list(dir)
+	i := sort.SearchStrings(names, prefix)
+	return i < len(names) && strings.HasPrefix(names[i], prefix)
+}
@@ -0,0 +1,134 @@
+package scanner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// LockfileKind identifies the package manager flavour of a project's lockfile.
+type LockfileKind string
+
+const (
+	LockNone LockfileKind = ""
+	LockNPM  LockfileKind = "npm"
+	LockYarn LockfileKind = "yarn"
+	LockPNPM LockfileKind = "pnpm"
+	LockBun  LockfileKind = "bun"
+)
+
+// lockfiles lists known lockfile names in detection priority order.
+var lockfiles = []struct {
+	name string
+	kind LockfileKind
+}{
+	{"package-lock.json", LockNPM},
+	{"pnpm-lock.yaml", LockPNPM},
+	{"yarn.lock", LockYarn},
+	{"bun.lockb", LockBun},
+	{"bun.lock", LockBun},
+}
+
+// ProjectInfo describes the project a node_modules directory belongs to,
+// as read from its sibling package.json and lockfile.
+type ProjectInfo struct {
+	Dir            string
+	Name           string
+	Version        string
+	Private        bool
+	Workspaces     []string
+	Lockfile       LockfileKind
+	LockfilePath   string
+	HasPackageJSON bool
+}
+
+// DisplayName returns "name@version" when known, falling back to the project
+// directory's base name.
+func (p ProjectInfo) DisplayName() string {
+	if p.Name == "" {
+		return filepath.Base(p.Dir)
+	}
+	if p.Version == "" {
+		return p.Name
+	}
+	return p.Name + "@" + p.Version
+}
+
+// packageJSON is the subset of package.json fields we care about.
+type packageJSON struct {
+	Name       string          `json:"name"`
+	Version    string          `json:"version"`
+	Private    bool            `json:"private"`
+	Workspaces json.RawMessage `json:"workspaces"`
+}
+
+// readProject collects metadata for the project rooted at dir. Missing or
+// malformed files are not errors; the corresponding fields stay empty.
+func readProject(dir string) ProjectInfo {
+	info := ProjectInfo{Dir: dir}
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		info.HasPackageJSON = true
+		var pj packageJSON
+		if json.Unmarshal(data, &pj) == nil {
+			info.Name = pj.Name
+			info.Version = pj.Version
+			info.Private = pj.Private
+			info.Workspaces = parseWorkspaces(pj.Workspaces)
+		}
+	}
+	info.Lockfile, info.LockfilePath = detectLockfile(dir)
+	return info
+}
+
+// workspaceRoot walks up from a project without a lockfile of its own to
+// the nearest directory with one, and returns that project when it is a
+// workspace root (package.json "workspaces" or pnpm-workspace.yaml), whose
+// lockfile then covers dir as a member.
+func workspaceRoot(dir string) (ProjectInfo, bool) {
+	for cur := filepath.Dir(dir); ; {
+		if kind, _ := detectLockfile(cur); kind != LockNone {
+			root := readProject(cur)
+			if len(root.Workspaces) == 0 {
+				if _, err := os.Stat(filepath.Join(cur, "pnpm-workspace.yaml")); err != nil {
+					return ProjectInfo{}, false
+				}
+			}
+			return root, true
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return ProjectInfo{}, false
+		}
+		cur = parent
+	}
+}
+
+// parseWorkspaces accepts both the array form and the yarn-style
+// {"packages": [...]} object form of the workspaces field.
+func parseWorkspaces(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		return list
+	}
+	var obj struct {
+		Packages []string `json:"packages"`
+	}
+	if json.Unmarshal(raw, &obj) == nil {
+		return obj.Packages
+	}
+	return nil
+}
+
+// detectLockfile returns the first known lockfile found in dir.
+func detectLockfile(dir string) (LockfileKind, string) {
+	for _, lf := range lockfiles {
+		p := filepath.Join(dir, lf.name)
+		if st, err := os.Stat(p); err == nil && !st.IsDir() {
+			return lf.kind, p
+		}
+	}
+	return LockNone, ""
+}
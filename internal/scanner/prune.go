@@ -0,0 +1,354 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNoReferences is returned by PlanPrune when no scanned project uses the
+// package manager: everything would look unused, so nothing is pruned.
+var ErrNoReferences = errors.New("no scanned project uses this cache; refusing to prune it")
+
+// PruneEntry is a file or directory removed by a cache cleanup.
+type PruneEntry struct {
+	Path  string
+	Size  int64
+	Files int64
+}
+
+// PrunePlan lists what the cleanup of one cache would remove. Nothing is
+// deleted while planning; callers hand the entries to the deleter.
+type PrunePlan struct {
+	Cache   Cache
+	Action  string // what the cleanup does, for display
+	Entries []PruneEntry
+	Size    int64 // apparent bytes of Entries
+	Kept    int   // entries left in place because they are still referenced
+}
+
+func (p *PrunePlan) add(path string, info fs.FileInfo) {
+	e := PruneEntry{Path: path, Size: info.Size(), Files: 1}
+	if info.IsDir() {
+		u, _ := dirSize(context.Background(), path, false, nil, budget{})
+		e.Size, e.Files = u.apparent, u.files+u.dirs+u.symlinks
+	}
+	p.Entries = append(p.Entries, e)
+	p.Size += e.Size
+}
+
+// PlanPrune works out the safe cleanup of c. What is kept depends on the
+// cache kind:
+//   - npm: content still listed in the cache index (like `npm cache verify`)
+//   - pnpm-store: packages locked by a scanned pnpm project, and files still
+//     hardlinked into some node_modules
+//   - yarn, yarn-berry: packages locked by a scanned yarn project; a
+//     project's .yarn/cache only keeps what its own lockfile locks
+//   - bun: packages installed in the node_modules of a scanned bun project
+//
+// Temporary directories are always removed. projects normally comes from
+// ScanCaches; with no project of the cache's package manager among them
+// PlanPrune returns ErrNoReferences.
+func PlanPrune(ctx context.Context, c Cache, projects []ProjectInfo) (PrunePlan, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	plan := PrunePlan{Cache: c}
+	var err error
+	switch c.Kind {
+	case CacheNPM:
+		plan.Action = "remove content no longer in the cache index"
+		err = planNPMPrune(ctx, &plan)
+	case CachePNPM:
+		plan.Action = "remove packages no scanned project locks"
+		err = planPNPMPrune(ctx, &plan, projects)
+	case CacheYarn, CacheYarnBerry:
+		plan.Action = "remove packages no scanned project locks"
+		if c.Project != "" {
+			plan.Action = "remove packages the project's lockfile no longer locks"
+			projects = []ProjectInfo{readProject(c.Project)}
+		}
+		err = planYarnPrune(ctx, &plan, projects)
+	case CacheBun:
+		plan.Action = "remove packages no scanned project has installed"
+		err = planBunPrune(ctx, &plan, projects)
+	default:
+		err = fmt.Errorf("unknown cache kind %q", c.Kind)
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	return plan, err
+}
+
+// planNPMPrune keeps the content that an index-v5 entry points to. Index
+// bucket lines are "<hash>\t<entry json>".
+func planNPMPrune(ctx context.Context, plan *PrunePlan) error {
+	dir := plan.Cache.Path
+	refs := map[string]bool{}
+	_ = filepath.WalkDir(filepath.Join(dir, "index-v5"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return ctx.Err()
+		}
+		scanLines(path, func(line string) {
+			_, entry, ok := strings.Cut(line, "\t")
+			if !ok {
+				return
+			}
+			var e struct {
+				Integrity string `json:"integrity"`
+			}
+			if json.Unmarshal([]byte(entry), &e) != nil {
+				return
+			}
+			for _, sri := range strings.Fields(e.Integrity) {
+				if algo, digest, ok := integrityHex(sri); ok {
+					refs[algo+"/"+digest] = true
+				}
+			}
+		})
+		return ctx.Err()
+	})
+	if len(refs) == 0 {
+		return errors.New("npm cache index is empty or unreadable; refusing to prune")
+	}
+	content := filepath.Join(dir, "content-v2")
+	err := filepath.WalkDir(content, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return ctx.Err()
+		}
+		// content-v2/<algo>/<xx>/<yy>/<rest of the digest>
+		rel, _ := filepath.Rel(content, path)
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) != 4 || refs[parts[0]+"/"+parts[1]+parts[2]+parts[3]] {
+			plan.Kept++
+			return ctx.Err()
+		}
+		if info, err := d.Info(); err == nil {
+			plan.add(path, info)
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return err
+	}
+	addTemp(plan, filepath.Join(dir, "tmp"))
+	return nil
+}
+
+// planPNPMPrune removes the package index of every package no scanned
+// lockfile locks, then the content files that no remaining index lists and
+// that are not hardlinked anywhere else. Store versions without package
+// indexes next to the content (a layout not understood here) are left alone.
+func planPNPMPrune(ctx context.Context, plan *PrunePlan, projects []ProjectInfo) error {
+	locked := map[string]bool{}
+	found := false
+	for _, p := range projects {
+		if p.Lockfile != LockPNPM {
+			continue
+		}
+		found = true
+		for _, pkg := range pnpmLockedPackages(p.LockfilePath) {
+			if _, digest, ok := integrityHex(pkg.integrity); ok {
+				locked[digest] = true
+			}
+		}
+	}
+	if !found {
+		return ErrNoReferences
+	}
+	versions, _ := os.ReadDir(plan.Cache.Path)
+	for _, v := range versions {
+		if !v.IsDir() || !isVersionDir(v.Name()) {
+			continue
+		}
+		addTemp(plan, filepath.Join(plan.Cache.Path, v.Name(), "tmp"))
+		files := filepath.Join(plan.Cache.Path, v.Name(), "files")
+		buckets, err := os.ReadDir(files)
+		if err != nil {
+			continue
+		}
+		// first pass: package indexes, remembering what the kept ones list
+		listed := map[string]bool{}
+		var content []string
+		indexes := 0
+		for _, b := range buckets {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			entries, _ := os.ReadDir(filepath.Join(files, b.Name()))
+			for _, e := range entries {
+				path := filepath.Join(files, b.Name(), e.Name())
+				if !strings.HasSuffix(e.Name(), "-index.json") {
+					content = append(content, path)
+					continue
+				}
+				indexes++
+				if !locked[b.Name()+strings.TrimSuffix(e.Name(), "-index.json")] {
+					if info, err := e.Info(); err == nil {
+						plan.add(path, info)
+					}
+					continue
+				}
+				plan.Kept++
+				for _, digest := range pnpmIndexFiles(path) {
+					listed[digest] = true
+				}
+			}
+		}
+		if indexes == 0 {
+			continue
+		}
+		// second pass: content files
+		for _, path := range content {
+			digest := filepath.Base(filepath.Dir(path)) + strings.TrimSuffix(filepath.Base(path), "-exec")
+			info, err := os.Lstat(path)
+			if err != nil {
+				continue
+			}
+			st, ok := statOf(info)
+			if listed[digest] || !ok || st.nlink > 1 {
+				plan.Kept++
+				continue
+			}
+			plan.add(path, info)
+		}
+	}
+	return nil
+}
+
+// pnpmIndexFiles returns the hex digests of the files a package index lists.
+func pnpmIndexFiles(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var idx struct {
+		Files map[string]struct {
+			Integrity string `json:"integrity"`
+		} `json:"files"`
+	}
+	if json.Unmarshal(data, &idx) != nil {
+		return nil
+	}
+	var out []string
+	for _, f := range idx.Files {
+		if _, digest, ok := integrityHex(f.Integrity); ok {
+			out = append(out, digest)
+		}
+	}
+	return out
+}
+
+// planYarnPrune keeps the cache entries of packages the projects' yarn
+// lockfiles lock; entries that are not registry packages (git, patches) are
+// always kept.
+func planYarnPrune(ctx context.Context, plan *PrunePlan, projects []ProjectInfo) error {
+	var prefixes []string
+	found := false
+	for _, p := range projects {
+		if p.Lockfile != LockYarn {
+			continue
+		}
+		pkgs := yarnLockedPackages(p.LockfilePath)
+		if pkgs == nil {
+			continue
+		}
+		found = true
+		for _, pkg := range pkgs {
+			flat := strings.ReplaceAll(pkg.name, "/", "-")
+			prefixes = append(prefixes, "npm-"+flat+"-"+pkg.version+"-", flat+"-npm-"+pkg.version+"-")
+		}
+	}
+	if !found {
+		return ErrNoReferences
+	}
+	listings := newDirListings()
+	for _, dir := range yarnEntryDirs([]string{plan.Cache.Path}, listings) {
+		names := listings.list(dir)
+		kept := make([]bool, len(names))
+		for _, prefix := range prefixes {
+			for i := sort.SearchStrings(names, prefix); i < len(names) && strings.HasPrefix(names[i], prefix); i++ {
+				kept[i] = true
+			}
+		}
+		for i, name := range names {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			registry := strings.HasPrefix(name, "npm-") || strings.Contains(name, "-npm-")
+			if kept[i] || !registry {
+				if registry {
+					plan.Kept++
+				}
+				continue
+			}
+			path := filepath.Join(dir, name)
+			if info, err := os.Lstat(path); err == nil {
+				plan.add(path, info)
+			}
+		}
+		addTemp(plan, filepath.Join(dir, ".tmp"))
+	}
+	return nil
+}
+
+// planBunPrune keeps the cache entries ("name@version@@@1") of packages
+// installed in the node_modules of scanned bun projects; bun's binary
+// lockfile is not read.
+func planBunPrune(ctx context.Context, plan *PrunePlan, projects []ProjectInfo) error {
+	installed := map[string]bool{}
+	found := false
+	for _, p := range projects {
+		if p.Lockfile != LockBun {
+			continue
+		}
+		found = true
+		indexPackages(filepath.Join(p.Dir, "node_modules"), func(name, version, _ string) {
+			installed[name+"@"+version] = true
+		})
+	}
+	if !found {
+		return ErrNoReferences
+	}
+	consider := func(dir, scope string) {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			pkg, _, ok := strings.Cut(e.Name(), "@@@")
+			if !ok || !e.IsDir() {
+				continue
+			}
+			if installed[scope+pkg] {
+				plan.Kept++
+				continue
+			}
+			if info, err := e.Info(); err == nil {
+				plan.add(filepath.Join(dir, e.Name()), info)
+			}
+		}
+	}
+	consider(plan.Cache.Path, "")
+	entries, _ := os.ReadDir(plan.Cache.Path)
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if e.IsDir() && strings.HasPrefix(e.Name(), "@") && !strings.Contains(e.Name(), "@@@") {
+			consider(filepath.Join(plan.Cache.Path, e.Name()), e.Name()+"/")
+		}
+	}
+	return nil
+}
+
+// addTemp adds a leftover temporary directory to the plan.
+func addTemp(plan *PrunePlan, dir string) {
+	if info, err := os.Lstat(dir); err == nil && info.IsDir() {
+		plan.add(dir, info)
+	}
+}
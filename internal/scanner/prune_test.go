@@ -0,0 +1,131 @@
+package scanner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// prunedPaths lists the plan's entries relative to base.
+func prunedPaths(plan PrunePlan, base string) []string {
+	var out []string
+	for _, e := range plan.Entries {
+		rel, _ := filepath.Rel(base, e.Path)
+		out = append(out, filepath.ToSlash(rel))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestPlanPrune_PNPM(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("link counts are not available")
+	}
+	store := t.TempDir()
+	project := t.TempDir()
+	writeFiles(t, project, map[string]string{
+		"package.json": `{"name": "app"}`,
+		"pnpm-lock.yaml": `lockfileVersion: '9.0'
+
+packages:
+
+  a@1.0.0:
+    resolution: {integrity: ` + sri("aa01") + `}
+
+  c@1.0.0:
+    resolution: {integrity: ` + sri("ff04") + `, tarball: https://npm.example.com/c/-/c-1.0.0.tgz}
+
+  '@esbuild/linux-x64@0.19.0':
+    resolution: {integrity: ` + sri("ab05") + `}
+    cpu: [x64]
+    os: [linux]
+    optional: true
+`,
+	})
+	writeFiles(t, store, map[string]string{
+		// a@1.0.0 is locked and lists one file; b is not locked
+		"v3/files/aa/01-index.json": `{"files": {"index.js": {"integrity": "` + sri("cc01") + `", "mode": 420}}}`,
+		"v3/files/bb/02-index.json": `{"files": {"index.js": {"integrity": "` + sri("dd02") + `", "mode": 420}}}`,
+		// c@1.0.0 is locked with a tarball resolution
+		"v3/files/ff/04-index.json": `{"files": {}}`,
+		// the installed optional @esbuild/linux-x64 is locked too
+		"v3/files/ab/05-index.json": `{"files": {"bin/esbuild": {"integrity": "` + sri("ac06") + `", "mode": 493}}}`,
+		"v3/files/ac/06-exec":       "esbuild",
+		"v3/files/cc/01":            "a",
+		"v3/files/dd/02":            "b",
+		"v3/files/ee/03-exec":       "linked",
+		"v3/tmp/partial":            "x",
+	})
+	// a file hardlinked into some node_modules stays
+	if err := os.Link(filepath.Join(store, "v3/files/ee/03-exec"), filepath.Join(project, "bin")); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+
+	plan, err := PlanPrune(nil, Cache{Kind: CachePNPM, Path: store}, []ProjectInfo{readProject(project)})
+	if err != nil {
+		t.Fatalf("PlanPrune: %v", err)
+	}
+	want := []string{"v3/files/bb/02-index.json", "v3/files/dd/02", "v3/tmp"}
+	if got := prunedPaths(plan, store); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("pruned %v, want %v", got, want)
+	}
+	if plan.Kept != 6 {
+		t.Errorf("kept %d, want 6", plan.Kept)
+	}
+
+	if _, err := PlanPrune(nil, Cache{Kind: CachePNPM, Path: store}, nil); !errors.Is(err, ErrNoReferences) {
+		t.Fatalf("expected ErrNoReferences without projects, got %v", err)
+	}
+}
+
+func TestPlanPrune_YarnProjectCache(t *testing.T) {
+	project := t.TempDir()
+	writeFiles(t, project, map[string]string{
+		"package.json": `{"name": "app"}`,
+		"yarn.lock": `__metadata:
+  version: 6
+
+"@scope/a@npm:^1.0.0":
+  version: 1.1.0
+  resolution: "@scope/a@npm:1.1.0"
+`,
+		".yarn/cache/@scope-a-npm-1.1.0-abc-8c.zip": "keep",
+		".yarn/cache/@scope-a-npm-1.0.0-def-8c.zip": "old",
+		".yarn/cache/b-patch-1a2b3c-8c.zip":         "not a registry package",
+		".yarn/cache/.gitignore":                    "",
+	})
+	cache := Cache{Kind: CacheYarnBerry, Path: filepath.Join(project, ".yarn", "cache"), Project: project}
+	plan, err := PlanPrune(nil, cache, nil)
+	if err != nil {
+		t.Fatalf("PlanPrune: %v", err)
+	}
+	want := []string{"@scope-a-npm-1.0.0-def-8c.zip"}
+	if got := prunedPaths(plan, cache.Path); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("pruned %v, want %v", got, want)
+	}
+}
+
+func TestPlanPrune_NPM(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"index-v5/12/34/5678":        "abc\t" + `{"key": "make-fetch-happen:request-cache:a", "integrity": "` + sri("aabbcc") + `"}`,
+		"content-v2/sha512/aa/bb/cc": "in the index",
+		"content-v2/sha512/dd/ee/ff": "garbage",
+		"tmp/abc":                    "",
+	})
+	plan, err := PlanPrune(nil, Cache{Kind: CacheNPM, Path: dir}, nil)
+	if err != nil {
+		t.Fatalf("PlanPrune: %v", err)
+	}
+	want := []string{"content-v2/sha512/dd/ee/ff", "tmp"}
+	if got := prunedPaths(plan, dir); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("pruned %v, want %v", got, want)
+	}
+	if plan.Size != int64(len("garbage")) {
+		t.Errorf("size %d, want %d", plan.Size, len("garbage"))
+	}
+}
@@ -0,0 +1,285 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Restorability estimates whether a deleted node_modules can be reinstalled
+// offline from the package manager's local cache.
+type Restorability struct {
+	Lockfile bool // the project has a lockfile to reinstall from
+	Packages int  // packages the lockfile pins: the estimated reinstall
+	Cached   int  // of those, found in the local npm cache, pnpm store or yarn cache
+
+	// Percent is Cached as a share of Packages, 100 for a lockfile without
+	// packages and 0 without a lockfile or when the lockfile cannot be read
+	// (bun's binary lockfile).
+	Percent int
+}
+
+// lockedPackage is one tarball a lockfile pins.
+type lockedPackage struct {
+	name, version, integrity string
+	optional                 bool // may be skipped on this platform
+}
+
+// checkRestorable looks up every package of the project's lockfile, or of
+// its workspace root's for a member, in the cache of the project's package
+// manager. It returns nil for targets other than node_modules.
+func checkRestorable(kind string, project ProjectInfo, listings *dirListings) *Restorability {
+	if kind != KindNodeModules {
+		return nil
+	}
+	if project.LockfilePath == "" {
+		if root, ok := workspaceRoot(project.Dir); ok {
+			project = root
+		}
+	}
+	r := &Restorability{Lockfile: project.LockfilePath != ""}
+	if !r.Lockfile {
+		return r
+	}
+	var pkgs []lockedPackage
+	var cached func(lockedPackage) bool
+	switch project.Lockfile {
+	case LockNPM:
+		pkgs = npmLockedPackages(project.LockfilePath)
+		dir := npmCacheDir()
+		cached = func(p lockedPackage) bool { return npmCached(dir, p.integrity) }
+	case LockPNPM:
+		pkgs = pnpmLockedPackages(project.LockfilePath)
+		stores := pnpmStoreDirs()
+		cached = func(p lockedPackage) bool { return pnpmStored(stores, p.integrity) }
+	case LockYarn:
+		pkgs = yarnLockedPackages(project.LockfilePath)
+		dirs := yarnEntryDirs(yarnCacheDirs(project.Dir), listings)
+		cached = func(p lockedPackage) bool { return yarnCached(dirs, listings, p) }
+	}
+	if pkgs == nil {
+		return r
+	}
+	for _, p := range pkgs {
+		// optional packages for other platforms are neither installed nor cached
+		if p.optional {
+			continue
+		}
+		r.Packages++
+		if cached(p) {
+			r.Cached++
+		}
+	}
+	r.Percent = 100
+	if r.Packages > 0 {
+		r.Percent = r.Cached * 100 / r.Packages
+	}
+	return r
+}
+
+// CheckRestorable estimates the restorability of the node_modules at path
+// from the lockfile of the project next to it.
+func CheckRestorable(path string) *Restorability {
+	return checkRestorable(KindNodeModules, readProject(filepath.Dir(path)), newDirListings())
+}
+
+// integrityHex splits the first hash of an SRI string such as
+// "sha512-<base64>" into its algorithm and hex digest.
+func integrityHex(integrity string) (algo, digest string, ok bool) {
+	first := strings.Fields(integrity)
+	if len(first) == 0 {
+		return "", "", false
+	}
+	algo, b64, ok := strings.Cut(first[0], "-")
+	if !ok {
+		return "", "", false
+	}
+	raw, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return "", "", false
+	}
+	return algo, hex.EncodeToString(raw), true
+}
+
+// npmCached checks npm's content store, where a tarball lives at
+// content-v2/<algo>/<xx>/<yy>/<rest of the hex digest>.
+func npmCached(cacheDir, integrity string) bool {
+	algo, digest, ok := integrityHex(integrity)
+	if !ok || cacheDir == "" || len(digest) < 5 {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(cacheDir, "content-v2", algo, digest[:2], digest[2:4], digest[4:]))
+	return err == nil
+}
+
+// pnpmStored checks the pnpm store's package index, files/<xx>/<rest>-index.json
+// keyed by the hex digest of the tarball integrity.
+func pnpmStored(stores []string, integrity string) bool {
+	_, digest, ok := integrityHex(integrity)
+	if !ok || len(digest) < 3 {
+		return false
+	}
+	for _, s := range stores {
+		if _, err := os.Stat(filepath.Join(s, "files", digest[:2], digest[2:]+"-index.json")); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// yarnEntryDirs expands yarn cache roots to the directories holding the
+// entries: yarn classic keeps them in a versioned subdirectory such as v6.
+func yarnEntryDirs(roots []string, listings *dirListings) []string {
+	var dirs []string
+	for _, root := range roots {
+		dirs = append(dirs, root)
+		for _, name := range listings.list(root) {
+			if isVersionDir(name) {
+				dirs = append(dirs, filepath.Join(root, name))
+			}
+		}
+	}
+	return dirs
+}
+
+// yarnCached matches cache entries by name and version: yarn classic's
+// "npm-@scope-name-1.0.0-<hash>-integrity" directories and yarn berry's
+// "@scope-name-npm-1.0.0-<hash>.zip" archives.
+func yarnCached(dirs []string, listings *dirListings, p lockedPackage) bool {
+	flat := strings.ReplaceAll(p.name, "/", "-")
+	classic := "npm-" + flat + "-" + p.version + "-"
+	berry := flat + "-npm-" + p.version + "-"
+	for _, d := range dirs {
+		if listings.hasPrefix(d, classic) || listings.hasPrefix(d, berry) {
+			return true
+		}
+	}
+	return false
+}
+
+// npmLockedPackages lists the registry tarballs of a v2/v3 package-lock.json.
+// Links, bundled dependencies and optional packages (often for another
+// platform) are left out.
+func npmLockedPackages(path string) []lockedPackage {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var lock struct {
+		Packages map[string]struct {
+			Name      string `json:"name"`
+			Version   string `json:"version"`
+			Integrity string `json:"integrity"`
+			Link      bool   `json:"link"`
+			InBundle  bool   `json:"inBundle"`
+			Optional  bool   `json:"optional"`
+		} `json:"packages"`
+	}
+	if json.Unmarshal(data, &lock) != nil || lock.Packages == nil {
+		return nil
+	}
+	pkgs := []lockedPackage{}
+	for loc, p := range lock.Packages {
+		i := strings.LastIndex(loc, "node_modules/")
+		if i < 0 || p.Link || p.InBundle || p.Optional {
+			continue
+		}
+		name := p.Name
+		if name == "" {
+			name = loc[i+len("node_modules/"):]
+		}
+		pkgs = append(pkgs, lockedPackage{name: name, version: p.Version, integrity: p.Integrity})
+	}
+	return pkgs
+}
+
+// pnpmLockedPackages lists the packages section of a pnpm lockfile with the
+// integrity of each resolution, marking optional packages.
+func pnpmLockedPackages(path string) []lockedPackage {
+	var pkgs []lockedPackage
+	seen := map[string]bool{}
+	var cur *lockedPackage
+	flush := func() {
+		if cur != nil && !seen[cur.name+"@"+cur.version] {
+			seen[cur.name+"@"+cur.version] = true
+			pkgs = append(pkgs, *cur)
+		}
+		cur = nil
+	}
+	inPackages := false
+	if !scanLines(path, func(line string) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case !strings.HasPrefix(line, " "):
+			flush()
+			inPackages = trimmed == "packages:"
+		case !inPackages:
+		case !strings.HasPrefix(line, "   ") && strings.HasSuffix(line, ":"):
+			flush()
+			entry := pnpmEntry(strings.Trim(strings.TrimSuffix(trimmed, ":"), `'"`))
+			at := strings.LastIndex(entry, "@")
+			if at <= 0 {
+				return
+			}
+			cur = &lockedPackage{name: entry[:at], version: entry[at+1:]}
+		case cur != nil && strings.HasPrefix(trimmed, "resolution: {"):
+			// {integrity: X} or {integrity: X, tarball: URL}
+			if _, v, ok := strings.Cut(trimmed, "integrity: "); ok {
+				if end := strings.IndexAny(v, ",}"); end >= 0 {
+					v = v[:end]
+				}
+				cur.integrity = strings.TrimSpace(v)
+			}
+		case cur != nil && trimmed == "optional: true":
+			cur.optional = true
+		}
+	}) {
+		return nil
+	}
+	flush()
+	if pkgs == nil {
+		pkgs = []lockedPackage{}
+	}
+	return pkgs
+}
+
+// yarnLockedPackages lists name@version of a yarn lockfile, classic or
+// berry. Workspace, patch and other non-registry resolutions are left out.
+func yarnLockedPackages(path string) []lockedPackage {
+	pkgs := []lockedPackage{}
+	seen := map[string]bool{}
+	add := func(name, version string) {
+		if name != "" && version != "" && !seen[name+"@"+version] {
+			seen[name+"@"+version] = true
+			pkgs = append(pkgs, lockedPackage{name: name, version: version})
+		}
+	}
+	var name string
+	if !scanLines(path, func(line string) {
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case !strings.HasPrefix(line, " "):
+			// classic: `a@^1.0.0, a@^1.1.0:`; take the name of the first pattern
+			first := strings.Trim(strings.TrimSpace(strings.Split(strings.TrimSuffix(line, ":"), ",")[0]), `"`)
+			name = ""
+			if at := strings.LastIndex(first, "@"); at > 0 && !strings.Contains(first, "@npm:") {
+				name = first[:at]
+			}
+		case strings.HasPrefix(line, "  version \"") && name != "":
+			add(name, strings.Trim(strings.TrimPrefix(line, "  version "), `"`))
+		case strings.HasPrefix(line, "  resolution: "):
+			// berry: `resolution: "a@npm:1.1.0"`
+			loc := strings.Trim(strings.TrimPrefix(line, "  resolution: "), `"`)
+			if n, v, ok := strings.Cut(loc, "@npm:"); ok {
+				add(n, v)
+			}
+		}
+	}) {
+		return nil
+	}
+	return pkgs
+}
@@ -0,0 +1,180 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+)
+
+// sri builds an integrity string whose digest is the given hex.
+func sri(digest string) string {
+	raw, _ := hex.DecodeString(digest)
+	return "sha512-" + base64.StdEncoding.EncodeToString(raw)
+}
+
+// isolateCaches points every cache lookup into a fresh home directory.
+func isolateCaches(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	t.Setenv("npm_config_cache", filepath.Join(home, ".npm"))
+	t.Setenv("PNPM_STORE_DIR", filepath.Join(home, "pnpm-store"))
+	t.Setenv("YARN_CACHE_FOLDER", filepath.Join(home, "yarn-cache"))
+	t.Setenv("BUN_INSTALL_CACHE_DIR", filepath.Join(home, "bun-cache"))
+	for _, env := range []string{"PLAYWRIGHT_BROWSERS_PATH", "CYPRESS_CACHE_FOLDER", "PUPPETEER_CACHE_DIR", "ELECTRON_CACHE", "NVM_DIR", "FNM_DIR", "VOLTA_HOME"} {
+		t.Setenv(env, "")
+	}
+	return home
+}
+
+func TestCheckRestorable_NPM(t *testing.T) {
+	home := isolateCaches(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"package.json": `{"name": "app"}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "app"},
+			"node_modules/a": {"version": "1.0.0", "integrity": "` + sri("aabbccddeeff") + `"},
+			"node_modules/b": {"version": "2.0.0", "integrity": "` + sri("112233445566") + `"},
+			"node_modules/local": {"resolved": "packages/local", "link": true},
+			"node_modules/@esbuild/win32-x64": {"version": "0.19.0", "optional": true}}}`,
+	})
+	writeFiles(t, home, map[string]string{
+		".npm/_cacache/content-v2/sha512/aa/bb/ccddeeff": "tarball",
+	})
+
+	got := CheckRestorable(filepath.Join(dir, "node_modules"))
+	want := Restorability{Lockfile: true, Packages: 2, Cached: 1, Percent: 50}
+	if got == nil || *got != want {
+		t.Fatalf("restorability = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckRestorable_PNPM(t *testing.T) {
+	home := isolateCaches(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"package.json": `{"name": "app"}`,
+		"pnpm-lock.yaml": `lockfileVersion: '9.0'
+
+packages:
+
+  a@1.1.0:
+    resolution: {integrity: ` + sri("aabbcc") + `}
+
+  '@scope/b@2.0.0':
+    resolution: {integrity: ` + sri("ddeeff") + `, tarball: https://npm.example.com/@scope/b/-/b-2.0.0.tgz}
+
+  fsevents@2.3.3:
+    resolution: {integrity: ` + sri("001122") + `}
+    optional: true
+
+snapshots:
+
+  a@1.1.0: {}
+`,
+	})
+	writeFiles(t, home, map[string]string{
+		"pnpm-store/v3/files/aa/bbcc-index.json": "{}",
+		"pnpm-store/v3/files/dd/eeff-index.json": "{}",
+	})
+
+	got := CheckRestorable(filepath.Join(dir, "node_modules"))
+	want := Restorability{Lockfile: true, Packages: 2, Cached: 2, Percent: 100}
+	if got == nil || *got != want {
+		t.Fatalf("restorability = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckRestorable_Yarn(t *testing.T) {
+	home := isolateCaches(t)
+	classic := t.TempDir()
+	writeFiles(t, classic, map[string]string{
+		"package.json": `{"name": "app"}`,
+		"yarn.lock": `# yarn lockfile v1
+
+
+a@^1.0.0, a@^1.1.0:
+  version "1.1.0"
+  resolved "https://registry.yarnpkg.com/a/-/a-1.1.0.tgz#abc"
+
+"@scope/b@^2.0.0":
+  version "2.0.0"
+`,
+	})
+	writeFiles(t, home, map[string]string{
+		"yarn-cache/v6/npm-@scope-b-2.0.0-0123abcd-integrity/node_modules/@scope/b/package.json": "{}",
+	})
+	got := CheckRestorable(filepath.Join(classic, "node_modules"))
+	want := Restorability{Lockfile: true, Packages: 2, Cached: 1, Percent: 50}
+	if got == nil || *got != want {
+		t.Fatalf("classic restorability = %+v, want %+v", got, want)
+	}
+
+	berry := t.TempDir()
+	writeFiles(t, berry, map[string]string{
+		"package.json": `{"name": "app"}`,
+		"yarn.lock": `__metadata:
+  version: 6
+
+"a@npm:^1.0.0":
+  version: 1.1.0
+  resolution: "a@npm:1.1.0"
+
+"app@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "app@workspace:."
+`,
+		".yarn/cache/a-npm-1.1.0-4f2a9c1e3b-8c.zip": "zip",
+	})
+	got = CheckRestorable(filepath.Join(berry, "node_modules"))
+	want = Restorability{Lockfile: true, Packages: 1, Cached: 1, Percent: 100}
+	if got == nil || *got != want {
+		t.Fatalf("berry restorability = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckRestorable_WorkspaceMember(t *testing.T) {
+	home := isolateCaches(t)
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"package.json": `{"name": "mono", "private": true, "workspaces": ["packages/*"]}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "mono"},
+			"node_modules/a": {"version": "1.0.0", "integrity": "` + sri("aabbccddeeff") + `"},
+			"packages/app/node_modules/b": {"version": "2.0.0", "integrity": "` + sri("112233445566") + `"}}}`,
+		"packages/app/package.json": `{"name": "app"}`,
+		// a project nested in a non-workspace project is not covered
+		"packages/app/vendor/lib/package.json": `{"name": "lib"}`,
+	})
+	writeFiles(t, home, map[string]string{
+		".npm/_cacache/content-v2/sha512/aa/bb/ccddeeff": "tarball",
+		".npm/_cacache/content-v2/sha512/11/22/33445566": "tarball",
+	})
+
+	got := CheckRestorable(filepath.Join(root, "packages", "app", "node_modules"))
+	want := Restorability{Lockfile: true, Packages: 2, Cached: 2, Percent: 100}
+	if got == nil || *got != want {
+		t.Fatalf("member restorability = %+v, want %+v", got, want)
+	}
+
+	writeFiles(t, root, map[string]string{"packages/app/package-lock.json": `{"packages": {}}`})
+	if got := CheckRestorable(filepath.Join(root, "packages", "app", "vendor", "lib", "node_modules")); got == nil || got.Lockfile {
+		t.Fatalf("nested project restorability = %+v, want no lockfile", got)
+	}
+}
+
+func TestCheckRestorable_NoLockfile(t *testing.T) {
+	isolateCaches(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"package.json": `{"name": "app"}`})
+	got := CheckRestorable(filepath.Join(dir, "node_modules"))
+	if got == nil || got.Lockfile || got.Percent != 0 {
+		t.Fatalf("restorability = %+v, want no lockfile and 0%%", got)
+	}
+	if r := checkRestorable("next", readProject(dir), newDirListings()); r != nil {
+		t.Fatalf("expected nil for other kinds, got %+v", r)
+	}
+}
@@ -8,15 +8,57 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
-	"strings"
 	"sync"
+	"time"
 )
 
-// ResultItem represents a found node_modules directory and its computed size.
+// ResultItem represents a found node_modules (or other target) directory and
+// its computed size.
 type ResultItem struct {
 	Path string
-	Size int64
-	Err  error
+	Kind string // matched target kind, e.g. "node_modules" or "next"
+	Size int64  // apparent size: sum of file sizes
+	Err  *ScanError
+
+	// Reclaimable is the allocated disk space actually freed by deleting the
+	// directory: block-based, with hardlinks counted once and only when all
+	// their links are inside it, and inodes deduplicated across the scan.
+	Reclaimable int64
+
+	Project ProjectInfo // metadata of the owning project (sibling package.json)
+
+	// LastActivity is the newest mtime among the project's own files
+	// (node_modules excluded); zero when unknown.
+	LastActivity time.Time
+
+	// Entry counts; deletion and archiving time scale with these more than
+	// with bytes.
+	Files    int64 // regular files (anything not a directory or symlink)
+	Dirs     int64 // directories, the target itself included
+	Symlinks int64
+
+	Health Health // ok, orphan, empty or incomplete; see checkHealth
+
+	// Drift compares a node_modules with the project's lockfile; nil when
+	// there is nothing to compare (no lockfile, no install record, bun).
+	Drift *Drift
+
+	Git *GitInfo // nil outside a git work tree
+
+	// Restore estimates how much of a node_modules can be reinstalled from
+	// local caches without network; nil for other kinds.
+	Restore *Restorability
+
+	// Protected marks a target tracked in git: the deleter refuses it unless
+	// forced.
+	Protected bool
+
+	// Partial marks sizes as lower bounds: a MaxEntries, MaxBytes or
+	// DirTimeout budget ran out before the walk finished.
+	Partial bool
+
+	// Cached reports that the size came from the persistent index.
+	Cached bool
 }
 
 // Options defines scanning behavior.
@@ -24,82 +66,89 @@ type Options struct {
 	Concurrency   int      // workers for size calculation
 	MaxDepth      int      // -1 unlimited; 0 means only root
 	FollowSymlink bool     // whether to follow symlinks
-	Excludes      []string // glob patterns matched against full path and base name
+	Excludes      []string // gitignore-style patterns relative to the scan root
+
+	// IgnoreFiles honours .nmmignore files found during the walk. They use
+	// .gitignore syntax and apply to their own directory and below.
+	IgnoreFiles bool
+
+	// Targets lists the directories to collect; empty means DefaultTargets.
+	// node_modules is never descended into, whether targeted or not.
+	Targets []TargetSpec
+
+	// Age filters based on ResultItem.LastActivity; zero disables.
+	OlderThan time.Duration // keep projects idle for at least this long
+	NewerThan time.Duration // keep projects active within this window
+
+	// CacheDir holds the persistent size index (see DefaultCacheDir); empty
+	// disables it. RefreshCache ignores stored entries but still rewrites them.
+	CacheDir     string
+	RefreshCache bool
+
+	// Per-target measuring budgets; zero means unlimited. When one runs out
+	// the result is kept with Partial set instead of failing.
+	MaxEntries int64         // entries visited
+	MaxBytes   int64         // apparent bytes counted
+	DirTimeout time.Duration // wall-clock time
 }
 
-// ScanNodeModules walks from root to find node_modules folders and compute their sizes.
-// Returns the list of results, the total combined size, and a merged error (if any).
-func ScanNodeModules(ctx context.Context, root string, opts Options) ([]ResultItem, int64, error) {
-	if ctx == nil {
-		ctx = context.Background()
-	}
-	if opts.Concurrency <= 0 {
-		opts.Concurrency = runtime.NumCPU()
-		if opts.Concurrency < 1 {
-			opts.Concurrency = 1
+// withDefaults fills in zero-valued options.
+func (o Options) withDefaults() Options {
+	if o.Concurrency <= 0 {
+		o.Concurrency = runtime.NumCPU()
+		if o.Concurrency < 1 {
+			o.Concurrency = 1
 		}
 	}
-	if opts.MaxDepth == 0 { // treat zero-value as unlimited for convenience
-		opts.MaxDepth = -1
+	if o.MaxDepth == 0 { // treat zero-value as unlimited for convenience
+		o.MaxDepth = -1
 	}
+	return o
+}
 
-	// Gather candidates first (paths to node_modules). We still bound traversal by MaxDepth.
-	var candidates []string
-	var walkErrs []error
-
-	rootDepth := depthOf(root)
-	walkFn := func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			walkErrs = append(walkErrs, fmt.Errorf("walk error at %s: %w", path, err))
-			return nil // continue
-		}
-		// Exclusions
-		if d.IsDir() && excluded(path, opts.Excludes) {
-			return filepath.SkipDir
-		}
-		name := d.Name()
-		// Capture node_modules regardless of depth gating (we don't descend into it)
-		if name == "node_modules" && d.IsDir() {
-			candidates = append(candidates, path)
-			return filepath.SkipDir
-		}
-		// Depth control
-		if opts.MaxDepth >= 0 {
-			if depthOf(path)-rootDepth > opts.MaxDepth {
-				if d.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
-			}
-		}
-
-		// Symlink handling
-		if !opts.FollowSymlink {
-			if d.Type()&os.ModeSymlink != 0 && d.IsDir() {
-				return filepath.SkipDir
-			}
-		}
-		return nil
+// ScanNodeModules walks from root to find node_modules folders and compute their sizes.
+// Returns the list of results, the total combined size, and the directories
+// the walk could not read (nil when there were none). Targets that could not
+// be measured carry their error in ResultItem.Err instead.
+func ScanNodeModules(ctx context.Context, root string, opts Options) ([]ResultItem, int64, []*ScanError) {
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	opts = opts.withDefaults()
 
-	_ = filepath.WalkDir(root, walkFn)
+	// Gather candidates first (paths to targets). We still bound traversal by MaxDepth.
+	var candidates []candidate
+	var walkErrs []*ScanError
+	var cmu sync.Mutex
+	discover(ctx, root, opts, func(c candidate) {
+		cmu.Lock()
+		candidates = append(candidates, c)
+		cmu.Unlock()
+	}, func(path string, err error) {
+		cmu.Lock()
+		walkErrs = append(walkErrs, newScanError(OpWalk, path, err))
+		cmu.Unlock()
+	})
 
 	// Compute sizes with a worker pool
-	type job struct{ path string }
-	jobs := make(chan job)
+	jobs := make(chan candidate)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	results := make([]ResultItem, 0, len(candidates))
 	var total int64
 
+	sess := newScanSession(opts)
 	worker := func() {
 		defer wg.Done()
 		for j := range jobs {
-			sz, err := dirSize(ctx, j.path, opts.FollowSymlink)
+			r, ok := sess.measure(ctx, j)
+			if !ok {
+				continue
+			}
 			mu.Lock()
-			results = append(results, ResultItem{Path: j.path, Size: sz, Err: err})
-			if err == nil {
-				total += sz
+			results = append(results, r)
+			if r.Err == nil {
+				total += r.Size
 			}
 			mu.Unlock()
 		}
@@ -115,56 +164,58 @@ func ScanNodeModules(ctx context.Context, root string, opts Options) ([]ResultIt
 	}
 	go func() {
 		defer close(jobs)
-		for _, p := range candidates {
+		for _, c := range candidates {
 			select {
 			case <-ctx.Done():
 				return
-			case jobs <- job{path: p}:
+			case jobs <- c:
 			}
 		}
 	}()
 	wg.Wait()
+	sess.close(ctx)
 
-	// Merge errors
-	return results, total, combineErrors(walkErrs)
+	return results, total, walkErrs
 }
 
-// ScanNodeModulesStream performs scanning and streams each computed ResultItem
-// on the returned channel as soon as it's available. When done, the results
-// channel is closed and a single error (if any) is sent on errCh then errCh is
-// closed. The total combined size can be computed by the caller incrementally.
-func ScanNodeModulesStream(ctx context.Context, root string, opts Options) (<-chan ResultItem, <-chan error) {
-	out := make(chan ResultItem)
-	errCh := make(chan error, 1)
+// ScanNodeModulesStream scans like ScanNodeModules but reports progress as
+// events: Discovered as soon as a target is found, then Sized once it has
+// been measured, preceded by an Error when measuring failed. Walk failures
+// are sent as Error events too. The
+// channel is closed when the scan is complete or ctx is cancelled.
+func ScanNodeModulesStream(ctx context.Context, root string, opts Options) <-chan Event {
+	out := make(chan Event)
 	go func() {
 		defer close(out)
 		if ctx == nil {
 			ctx = context.Background()
 		}
-		if opts.Concurrency <= 0 {
-			opts.Concurrency = runtime.NumCPU()
-			if opts.Concurrency < 1 {
-				opts.Concurrency = 1
+		opts = opts.withDefaults()
+		send := func(ev Event) {
+			select {
+			case <-ctx.Done():
+			case out <- ev:
 			}
 		}
-		if opts.MaxDepth == 0 { // treat zero-value as unlimited
-			opts.MaxDepth = -1
-		}
-		var walkErrs []error
-		rootDepth := depthOf(root)
-		type job struct{ path string }
-		jobs := make(chan job)
-		var wg sync.WaitGroup
 
+		queue := newPending()
+		var wg sync.WaitGroup
+		sess := newScanSession(opts)
 		worker := func() {
 			defer wg.Done()
-			for j := range jobs {
-				sz, err := dirSize(ctx, j.path, opts.FollowSymlink)
-				select {
-				case <-ctx.Done():
+			for {
+				r, ok := queue.pop()
+				if !ok {
 					return
-				case out <- ResultItem{Path: j.path, Size: sz, Err: err}:
 				}
+				if ctx.Err() != nil {
+					continue
+				}
+				sess.size(ctx, &r)
+				if r.Err != nil {
+					send(Error{r.Err})
+				}
+				send(Sized{r})
 			}
 		}
 
@@ -177,63 +228,173 @@ func ScanNodeModulesStream(ctx context.Context, root string, opts Options) (<-ch
 			go worker()
 		}
 
-		walkFn := func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				walkErrs = append(walkErrs, fmt.Errorf("walk error at %s: %w", path, err))
-				return nil
-			}
-			if d.IsDir() && excluded(path, opts.Excludes) {
-				return filepath.SkipDir
-			}
-			name := d.Name()
-			if name == "node_modules" && d.IsDir() {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case jobs <- job{path: path}:
-				}
-				return filepath.SkipDir
-			}
-			if opts.MaxDepth >= 0 {
-				if depthOf(path)-rootDepth > opts.MaxDepth {
-					if d.IsDir() {
-						return filepath.SkipDir
-					}
-					return nil
-				}
-			}
-			if !opts.FollowSymlink {
-				if d.Type()&os.ModeSymlink != 0 && d.IsDir() {
-					return filepath.SkipDir
-				}
+		discover(ctx, root, opts, func(c candidate) {
+			r, ok := sess.prepare(ctx, c)
+			if !ok {
+				return
 			}
-			return nil
-		}
-
-		// feed jobs via walk
-		go func() {
-			_ = filepath.WalkDir(root, walkFn)
-			close(jobs)
-		}()
+			send(Discovered{r})
+			queue.push(r)
+		}, func(path string, err error) {
+			send(Error{newScanError(OpWalk, path, err)})
+		})
+		queue.close()
 		wg.Wait()
-		// emit merged walk errors
-		errCh <- combineErrors(walkErrs)
-		close(errCh)
+		sess.close(ctx)
 	}()
-	return out, errCh
+	return out
 }
 
-// dirSize computes total size in bytes of a directory tree.
-func dirSize(ctx context.Context, root string, followSymlink bool) (int64, error) {
+// Measure prepares and sizes a single target directory with opts, e.g. to
+// re-measure a partial result with the budgets lifted. Age filters do not
+// apply, and inodes are not deduplicated against other results.
+func Measure(ctx context.Context, path, kind string, opts Options) ResultItem {
 	if ctx == nil {
 		ctx = context.Background()
 	}
-	seen := make(map[string]struct{})
-	return dirSizeRec(ctx, root, followSymlink, seen)
+	sess := newScanSession(opts.withDefaults())
+	r, _ := sess.prepare(ctx, candidate{path: path, kind: kind})
+	sess.size(ctx, &r)
+	sess.close(ctx)
+	return r
 }
 
-func dirSizeRec(ctx context.Context, root string, followSymlink bool, seen map[string]struct{}) (int64, error) {
-	var total int64
+// candidate is a discovered target directory awaiting measurement.
+type candidate struct {
+	path string
+	kind string
+}
+
+// scanSession holds the state shared by the size workers of one scan.
+type scanSession struct {
+	opts   Options
+	now    time.Time
+	claims *inodeClaims
+	index  *sizeIndex // nil when the persistent cache is disabled
+	git    *gitRepos
+	caches *dirListings // cache directory listings for restorability
+}
+
+func newScanSession(opts Options) *scanSession {
+	s := &scanSession{opts: opts, now: time.Now(), claims: newInodeClaims(), git: newGitRepos(), caches: newDirListings()}
+	if opts.CacheDir != "" {
+		s.index = openSizeIndex(opts.CacheDir, opts.RefreshCache)
+	}
+	return s
+}
+
+// close persists the size index unless the scan was cancelled.
+func (s *scanSession) close(ctx context.Context) {
+	if s.index != nil && ctx.Err() == nil {
+		_ = s.index.save()
+	}
+}
+
+// measure prepares and sizes a found target. It reports false when the
+// result is filtered out by the age options, in which case the size is not
+// computed.
+func (s *scanSession) measure(ctx context.Context, c candidate) (ResultItem, bool) {
+	r, ok := s.prepare(ctx, c)
+	if !ok {
+		return r, false
+	}
+	s.size(ctx, &r)
+	return r, true
+}
+
+// prepare gathers the cheap metadata about the project owning a found
+// target, so that it can be reported right away, and applies the age
+// filters. The last activity walks the project, so it is only read here
+// when a filter needs it.
+func (s *scanSession) prepare(ctx context.Context, c candidate) (ResultItem, bool) {
+	project := readProject(filepath.Dir(c.path))
+	r := ResultItem{
+		Path:    c.path,
+		Kind:    c.kind,
+		Project: project,
+		Health:  checkHealth(c.path, c.kind, project),
+	}
+	if s.opts.filtersByAge() {
+		r.LastActivity = lastActivity(ctx, project)
+	}
+	return r, s.opts.keepByAge(r, s.now)
+}
+
+// details fills in what reads lockfiles, git indexes and caches or walks the
+// project: drift, git state and protection, restorability and, unless
+// prepare already did, the last activity.
+func (s *scanSession) details(ctx context.Context, r *ResultItem) {
+	if !s.opts.filtersByAge() {
+		r.LastActivity = lastActivity(ctx, r.Project)
+	}
+	r.Drift = checkDrift(r.Path, r.Kind, r.Project)
+	r.Git = s.git.lookup(r.Project.Dir, r.Path)
+	r.Protected = r.Git != nil && r.Git.Tracked
+	r.Restore = checkRestorable(r.Kind, r.Project, s.caches)
+}
+
+// size fills in the details of r and its sizes, reusing the persistent index
+// when a node_modules directory's fingerprint is unchanged.
+func (s *scanSession) size(ctx context.Context, r *ResultItem) {
+	s.details(ctx, r)
+	indexed := s.index != nil && r.Kind == KindNodeModules
+	var fp uint64
+	if indexed {
+		fp = fingerprint(r.Path)
+		if e, ok := s.index.lookup(r.Path, fp, s.opts.FollowSymlink); ok {
+			r.Size, r.Reclaimable, r.Cached = e.Size, e.reclaimable(s.claims), true
+			r.Files, r.Dirs, r.Symlinks = e.Files, e.Dirs, e.Symlinks
+			return
+		}
+	}
+	u, err := dirSize(ctx, r.Path, s.opts.FollowSymlink, s.claims, s.opts.budget())
+	r.Size, r.Reclaimable, r.Partial = u.apparent, u.reclaimable, u.partial
+	if err != nil {
+		r.Err = newScanError(OpSize, r.Path, err)
+	}
+	r.Files, r.Dirs, r.Symlinks = u.files, u.dirs, u.symlinks
+	if indexed && err == nil && !u.partial && ctx.Err() == nil {
+		s.index.store(r.Path, fp, s.opts.FollowSymlink, u)
+	}
+}
+
+// dirSize computes the disk usage of a directory tree. claims is shared by
+// all results of one scan so that an inode is never reported as reclaimable
+// twice; it may be nil for a standalone measurement. When b runs out the walk
+// stops early and the usage is marked partial.
+func dirSize(ctx context.Context, root string, followSymlink bool, claims *inodeClaims, b budget) (usage, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if claims == nil {
+		claims = newInodeClaims()
+	}
+	s := &sizer{
+		ctx:           ctx,
+		followSymlink: followSymlink,
+		seen:          make(map[string]struct{}),
+		claims:        claims,
+		links:         make(map[fileID]*linkCount),
+		budget:        b,
+	}
+	err := s.walk(root)
+	s.settleLinks()
+	return s.u, err
+}
+
+// sizer carries the state of a single dirSize computation.
+type sizer struct {
+	ctx           context.Context
+	followSymlink bool
+	seen          map[string]struct{} // real paths of followed symlinked dirs
+	claims        *inodeClaims
+	links         map[fileID]*linkCount // multiply-linked inodes met in this tree
+	budget        budget
+	entries       int64
+	u             usage
+}
+
+func (s *sizer) walk(root string) error {
 	var firstErr error
 	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -243,36 +404,45 @@ func dirSizeRec(ctx context.Context, root string, followSymlink bool, seen map[s
 			return nil // continue
 		}
 		select {
-		case <-ctx.Done():
-			return ctx.Err()
+		case <-s.ctx.Done():
+			return s.ctx.Err()
 		default:
 		}
-		// handle symlinked directories
-		if d.IsDir() && d.Type()&os.ModeSymlink != 0 {
-			if followSymlink {
-				// follow to real target if it's a dir
-				if info, e := os.Stat(path); e == nil && info.IsDir() {
-					if real, e2 := filepath.EvalSymlinks(path); e2 == nil {
-						if _, ok := seen[real]; ok {
-							return filepath.SkipDir
-						}
-						seen[real] = struct{}{}
-						sz, e3 := dirSizeRec(ctx, real, followSymlink, seen)
-						if e3 != nil && firstErr == nil {
-							firstErr = e3
-						}
-						total += sz
-						return filepath.SkipDir
+		s.entries++
+		if s.budget.exhausted(s.entries, s.u) {
+			s.u.partial = true
+			return errBudget
+		}
+		isLink := d.Type()&os.ModeSymlink != 0
+		if isLink {
+			s.u.symlinks++
+		}
+		// handle symlinked directories (WalkDir never descends into them)
+		if isLink && s.followSymlink {
+			if info, e := os.Stat(path); e == nil && info.IsDir() {
+				if real, e2 := filepath.EvalSymlinks(path); e2 == nil {
+					if _, ok := s.seen[real]; ok {
+						return nil
+					}
+					s.seen[real] = struct{}{}
+					if e3 := s.walk(real); e3 != nil && firstErr == nil {
+						firstErr = e3
+					}
+					if s.u.partial {
+						return errBudget
 					}
 				}
+				return nil
 			}
-			// not following
-			return filepath.SkipDir
 		}
 		if d.IsDir() {
+			s.u.dirs++
 			return nil
 		}
-		// file (d.Info follows symlink for files)
+		if !isLink {
+			s.u.files++
+		}
+		// file or unfollowed symlink (d.Info does not follow links)
 		info, e := d.Info()
 		if e != nil {
 			if firstErr == nil {
@@ -280,17 +450,52 @@ func dirSizeRec(ctx context.Context, root string, followSymlink bool, seen map[s
 			}
 			return nil
 		}
-		total += info.Size()
+		s.add(info)
 		return nil
 	})
-	if err != nil && !errors.Is(err, context.Canceled) {
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, errBudget) {
 		if firstErr == nil {
 			firstErr = err
 		} else {
 			firstErr = fmt.Errorf("%v; %v", firstErr, err)
 		}
 	}
-	return total, firstErr
+	return firstErr
+}
+
+// add accounts a single non-directory entry.
+func (s *sizer) add(info fs.FileInfo) {
+	s.u.apparent += info.Size()
+	st, ok := statOf(info)
+	if !ok {
+		// no inode information on this platform; best effort
+		s.u.reclaimable += info.Size()
+		return
+	}
+	if st.nlink <= 1 {
+		if s.claims.claim(st.id) {
+			s.u.reclaimable += st.allocated
+		}
+		return
+	}
+	lc := s.links[st.id]
+	if lc == nil {
+		lc = &linkCount{nlink: st.nlink, allocated: st.allocated}
+		s.links[st.id] = lc
+	}
+	lc.seen++
+}
+
+// settleLinks counts multiply-linked inodes whose every link was found inside
+// the tree. Inodes with links elsewhere (e.g. pnpm's content-addressed store)
+// are not freed by deleting the tree and are left out.
+func (s *sizer) settleLinks() {
+	for id, lc := range s.links {
+		if lc.seen >= lc.nlink && s.claims.claim(id) {
+			s.u.reclaimable += lc.allocated
+			s.u.linked = append(s.u.linked, linkedInode{Dev: id.dev, Ino: id.ino, Allocated: lc.allocated})
+		}
+	}
 }
 
 func depthOf(p string) int {
@@ -311,52 +516,3 @@ func depthOf(p string) int {
 	}
 	return depth
 }
-
-func combineErrors(errs []error) error {
-	if len(errs) == 0 {
-		return nil
-	}
-	if len(errs) == 1 {
-		return errs[0]
-	}
-	var b strings.Builder
-	b.WriteString("multiple errors:")
-	for _, e := range errs {
-		if e == nil {
-			continue
-		}
-		b.WriteString("\n - ")
-		b.WriteString(e.Error())
-	}
-	return errors.New(b.String())
-}
-
-func excluded(p string, patterns []string) bool {
-	if len(patterns) == 0 {
-		return false
-	}
-	base := filepath.Base(p)
-	for _, pat := range patterns {
-		if pat == "" {
-			continue
-		}
-		// try full path
-		if ok, _ := filepath.Match(pat, p); ok {
-			return true
-		}
-		// simple contains fallback for patterns like "*/examples/*" or "*/a/*"
-		// trim leading/trailing '*' and check substring occurrence
-		trimmed := strings.Trim(pat, "*")
-		if trimmed != pat && trimmed != "" {
-			sub := strings.ReplaceAll(trimmed, "/", string(os.PathSeparator))
-			if strings.Contains(p, sub) {
-				return true
-			}
-		}
-		// try base name
-		if ok, _ := filepath.Match(pat, base); ok {
-			return true
-		}
-	}
-	return false
-}
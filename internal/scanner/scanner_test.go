@@ -1,9 +1,13 @@
 package scanner
 
 import (
+	"bytes"
+	"context"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func writeFileOfSize(t *testing.T, path string, size int64) {
@@ -116,3 +120,403 @@ func TestScanNodeModules_Exclude(t *testing.T) {
 		t.Fatalf("expected 0 results, got %d", len(results))
 	}
 }
+
+func TestScanNodeModules_IgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{
+		filepath.Join(root, "app", "node_modules"),
+		filepath.Join(root, "app", "test", "fixtures", "proj", "node_modules"),
+		filepath.Join(root, "app", "test", "fixtures", "keep", "node_modules"),
+	} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		writeFileOfSize(t, filepath.Join(d, "f"), 10)
+	}
+	ignore := []byte("# checked in on purpose\nfixtures/\n")
+	if err := os.WriteFile(filepath.Join(root, "app", ".nmmignore"), ignore, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// as with .gitignore, files inside an excluded directory are never read
+	if err := os.WriteFile(filepath.Join(root, "app", "test", "fixtures", ".nmmignore"), []byte("!keep\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	results, _, err := ScanNodeModules(nil, root, Options{IgnoreFiles: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != filepath.Join(root, "app", "node_modules") {
+		t.Fatalf("expected only app/node_modules, got %+v", results)
+	}
+
+	// without IgnoreFiles the fixtures are listed
+	results, _, err = ScanNodeModules(nil, root, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+}
+
+func TestScanNodeModulesStream_Events(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "a", "node_modules")
+	b := filepath.Join(root, "b", "node_modules")
+	for _, d := range []string{a, b} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	writeFileOfSize(t, filepath.Join(a, "x"), 100)
+	writeFileOfSize(t, filepath.Join(a, "y"), 50)
+	writeFileOfSize(t, filepath.Join(b, "z"), 10)
+	writeFileOfSize(t, filepath.Join(root, "a", "index.js"), 1)
+
+	discovered := map[string]bool{}
+	sized := map[string]Sized{}
+	for ev := range ScanNodeModulesStream(context.Background(), root, Options{}) {
+		switch e := ev.(type) {
+		case Discovered:
+			if e.Size != 0 {
+				t.Fatalf("discovered %s with size %d before measuring", e.Path, e.Size)
+			}
+			if !e.LastActivity.IsZero() {
+				t.Fatalf("discovered %s with its activity read without an age filter", e.Path)
+			}
+			discovered[e.Path] = true
+		case Sized:
+			if !discovered[e.Path] {
+				t.Fatalf("sized %s before it was discovered", e.Path)
+			}
+			sized[e.Path] = e
+		case Error:
+			t.Fatalf("unexpected error event: %v", e)
+		}
+	}
+	if len(sized) != 2 {
+		t.Fatalf("expected 2 sized events, got %d", len(sized))
+	}
+	if s := sized[a]; s.Size != 150 || s.Files != 2 || s.LastActivity.IsZero() {
+		t.Fatalf("a: got size %d files %d activity %v; want 150, 2 and the activity", s.Size, s.Files, s.LastActivity)
+	}
+	if s := sized[b]; s.Size != 10 || s.Files != 1 {
+		t.Fatalf("b: got size %d files %d; want 10, 1", s.Size, s.Files)
+	}
+}
+
+func TestScanNodeModules_Budgets(t *testing.T) {
+	root := t.TempDir()
+	nm := filepath.Join(root, "app", "node_modules")
+	if err := os.MkdirAll(nm, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		writeFileOfSize(t, filepath.Join(nm, string(rune('a'+i))), 100)
+	}
+
+	for name, opts := range map[string]Options{
+		"entries": {MaxEntries: 5},
+		"bytes":   {MaxBytes: 300},
+	} {
+		results, _, err := ScanNodeModules(nil, root, opts)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("%s: expected 1 result, got %d", name, len(results))
+		}
+		r := results[0]
+		if !r.Partial || r.Err != nil || r.Size <= 0 || r.Size >= 2000 {
+			t.Fatalf("%s: expected a partial lower bound, got %+v", name, r)
+		}
+	}
+
+	// re-measuring without budgets yields the full size
+	r := Measure(nil, nm, KindNodeModules, Options{})
+	if r.Partial || r.Size != 2000 || r.Files != 20 {
+		t.Fatalf("unexpected re-measure: partial=%v size=%d files=%d", r.Partial, r.Size, r.Files)
+	}
+}
+
+func TestScanNodeModules_Counts(t *testing.T) {
+	root := t.TempDir()
+	nm := filepath.Join(root, "app", "node_modules")
+	if err := os.MkdirAll(filepath.Join(nm, "a", "lib"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFileOfSize(t, filepath.Join(nm, "a", "index.js"), 10)
+	writeFileOfSize(t, filepath.Join(nm, "a", "lib", "x.js"), 10)
+	wantLinks := int64(0)
+	if runtime.GOOS != "windows" {
+		if err := os.MkdirAll(filepath.Join(nm, ".bin"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.Symlink("../a/index.js", filepath.Join(nm, ".bin", "a")); err != nil {
+			t.Fatalf("symlink: %v", err)
+		}
+		wantLinks = 1
+	}
+
+	results, _, err := ScanNodeModules(nil, root, Options{})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("unexpected scan: %v, %d results", err, len(results))
+	}
+	r := results[0]
+	wantDirs := int64(3) // node_modules, a, a/lib
+	if wantLinks > 0 {
+		wantDirs++ // .bin
+	}
+	if r.Files != 2 || r.Dirs != wantDirs || r.Symlinks != wantLinks {
+		t.Fatalf("counts: files=%d dirs=%d symlinks=%d; want 2, %d, %d", r.Files, r.Dirs, r.Symlinks, wantDirs, wantLinks)
+	}
+}
+
+func TestScanNodeModules_ProjectMetadata(t *testing.T) {
+	root := t.TempDir()
+	proj := filepath.Join(root, "app")
+	if err := os.MkdirAll(filepath.Join(proj, "node_modules"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	pkg := `{"name":"my-app","version":"1.2.0","private":true,"workspaces":{"packages":["packages/*"]}}`
+	if err := os.WriteFile(filepath.Join(proj, "package.json"), []byte(pkg), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	writeFileOfSize(t, filepath.Join(proj, "pnpm-lock.yaml"), 1)
+
+	results, _, err := ScanNodeModules(nil, root, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	p := results[0].Project
+	if p.Name != "my-app" || p.Version != "1.2.0" || !p.Private {
+		t.Fatalf("unexpected project metadata: %+v", p)
+	}
+	if len(p.Workspaces) != 1 || p.Workspaces[0] != "packages/*" {
+		t.Fatalf("unexpected workspaces: %v", p.Workspaces)
+	}
+	if p.Lockfile != LockPNPM {
+		t.Fatalf("lockfile mismatch: %q", p.Lockfile)
+	}
+	if got := p.DisplayName(); got != "my-app@1.2.0" {
+		t.Fatalf("DisplayName = %q", got)
+	}
+}
+
+func TestScanNodeModules_AgeFilter(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	for _, name := range []string{"stale", "fresh"} {
+		proj := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Join(proj, "node_modules"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		src := filepath.Join(proj, "index.js")
+		writeFileOfSize(t, src, 1)
+		if name == "stale" {
+			if err := os.Chtimes(src, old, old); err != nil {
+				t.Fatalf("chtimes: %v", err)
+			}
+		}
+	}
+	// node_modules content must not count as activity
+	writeFileOfSize(t, filepath.Join(root, "stale", "node_modules", "new.bin"), 1)
+
+	results, _, err := ScanNodeModules(nil, root, Options{OlderThan: 90 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || filepath.Base(filepath.Dir(results[0].Path)) != "stale" {
+		t.Fatalf("expected only the stale project, got %+v", results)
+	}
+	if d := results[0].LastActivity.Sub(old); d > time.Second || d < -time.Second {
+		t.Fatalf("LastActivity = %v, want %v", results[0].LastActivity, old)
+	}
+
+	results, _, err = ScanNodeModules(nil, root, Options{NewerThan: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || filepath.Base(filepath.Dir(results[0].Path)) != "fresh" {
+		t.Fatalf("expected only the fresh project, got %+v", results)
+	}
+}
+
+func TestScanNodeModules_ReclaimableHardlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("inode accounting is unix-only")
+	}
+	root := t.TempDir()
+	nm := filepath.Join(root, "a", "node_modules")
+	store := filepath.Join(root, "store")
+	for _, d := range []string{nm, store} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	real := filepath.Join(nm, "real.bin")
+	if err := os.WriteFile(real, bytes.Repeat([]byte{1}, 8192), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// second link inside the same tree: counted once
+	if err := os.Link(real, filepath.Join(nm, "link.bin")); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	// link into an outside store: deleting node_modules frees nothing
+	ext := filepath.Join(store, "ext.bin")
+	if err := os.WriteFile(ext, bytes.Repeat([]byte{2}, 4096), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Link(ext, filepath.Join(nm, "ext.bin")); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+
+	info, err := os.Stat(real)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	st, ok := statOf(info)
+	if !ok {
+		t.Skip("no inode information available")
+	}
+
+	results, total, errs := ScanNodeModules(nil, root, Options{})
+	if errs != nil {
+		t.Fatalf("unexpected error: %v", errs)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if want := int64(8192 + 8192 + 4096); total != want || results[0].Size != want {
+		t.Fatalf("apparent size mismatch: total %d size %d want %d", total, results[0].Size, want)
+	}
+	if results[0].Reclaimable != st.allocated {
+		t.Fatalf("reclaimable = %d, want %d", results[0].Reclaimable, st.allocated)
+	}
+}
+
+func TestScanNodeModules_Targets(t *testing.T) {
+	root := t.TempDir()
+	mk := func(parts ...string) string {
+		p := filepath.Join(append([]string{root}, parts...)...)
+		if err := os.MkdirAll(p, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		return p
+	}
+	writeFileOfSize(t, filepath.Join(mk("web", ".next"), "page.js"), 10)
+	writeFileOfSize(t, filepath.Join(root, "web", "next.config.js"), 1)
+	writeFileOfSize(t, filepath.Join(mk("web", "node_modules", "pkg", ".next"), "x"), 1)
+	// .next without next.config.* is not a target
+	writeFileOfSize(t, filepath.Join(mk("other", ".next"), "page.js"), 10)
+	writeFileOfSize(t, filepath.Join(mk("lib", ".turbo"), "log"), 5)
+
+	var specs []TargetSpec
+	for _, s := range []string{"node_modules", "next", ".turbo"} {
+		got, err := ParseTarget(s)
+		if err != nil {
+			t.Fatalf("ParseTarget(%q): %v", s, err)
+		}
+		specs = append(specs, got...)
+	}
+	results, _, err := ScanNodeModules(nil, root, Options{Targets: specs})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kinds := map[string]string{}
+	for _, r := range results {
+		rel, _ := filepath.Rel(root, r.Path)
+		kinds[filepath.ToSlash(rel)] = r.Kind
+	}
+	want := map[string]string{
+		"web/.next":        "next",
+		"web/node_modules": KindNodeModules,
+		"lib/.turbo":       "turbo",
+	}
+	if len(kinds) != len(want) {
+		t.Fatalf("results = %v, want %v", kinds, want)
+	}
+	for p, k := range want {
+		if kinds[p] != k {
+			t.Fatalf("results = %v, want %v", kinds, want)
+		}
+	}
+
+	if _, err := ParseTarget("a/b"); err == nil {
+		t.Fatalf("expected error for nested target name")
+	}
+}
+
+func TestScanNodeModules_Cache(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := t.TempDir()
+	nm := filepath.Join(root, "a", "node_modules")
+	if err := os.MkdirAll(filepath.Join(nm, "left-pad"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFileOfSize(t, filepath.Join(nm, "left-pad", "index.js"), 100)
+	opts := Options{CacheDir: cacheDir}
+
+	scan := func(o Options) ResultItem {
+		t.Helper()
+		results, _, err := ScanNodeModules(nil, root, o)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("expected 1 result, got %d", len(results))
+		}
+		return results[0]
+	}
+
+	if r := scan(opts); r.Cached || r.Size != 100 {
+		t.Fatalf("first scan: cached=%v size=%d", r.Cached, r.Size)
+	}
+	if r := scan(opts); !r.Cached || r.Size != 100 {
+		t.Fatalf("second scan: cached=%v size=%d", r.Cached, r.Size)
+	}
+	if r := scan(Options{CacheDir: cacheDir, RefreshCache: true}); r.Cached {
+		t.Fatalf("refresh scan should not use the cache")
+	}
+	// sizes measured without following symlinks are not reused with -L
+	if r := scan(Options{CacheDir: cacheDir, FollowSymlink: true}); r.Cached {
+		t.Fatalf("symlink-following scan should not use the cache")
+	}
+
+	// a cache hit claims the hardlinked inodes the stored walk counted
+	e := cacheEntry{Reclaimable: 8192, Linked: []linkedInode{{Dev: 1, Ino: 2, Allocated: 4096}}}
+	claims := newInodeClaims()
+	if got := e.reclaimable(claims); got != 8192 {
+		t.Fatalf("first hit reclaimable = %d, want 8192", got)
+	}
+	if got := e.reclaimable(claims); got != 4096 {
+		t.Fatalf("second hit reclaimable = %d, want 4096", got)
+	}
+
+	// installing a package changes the fingerprint
+	if err := os.MkdirAll(filepath.Join(nm, "is-odd"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFileOfSize(t, filepath.Join(nm, "is-odd", "index.js"), 50)
+	if r := scan(opts); r.Cached || r.Size != 150 {
+		t.Fatalf("after install: cached=%v size=%d", r.Cached, r.Size)
+	}
+
+	// build outputs are always measured: their top level says nothing
+	// about rebuilds
+	dist := filepath.Join(root, "a", "dist")
+	if err := os.MkdirAll(dist, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFileOfSize(t, filepath.Join(dist, "main.js"), 10)
+	distOpts := Options{CacheDir: cacheDir, Targets: []TargetSpec{{Kind: "dist", Name: "dist"}}}
+	for i := 0; i < 2; i++ {
+		if r := scan(distOpts); r.Cached {
+			t.Fatalf("scan %d: dist should not use the cache", i+1)
+		}
+	}
+}
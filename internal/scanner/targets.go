@@ -0,0 +1,140 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// KindNodeModules is the kind of the default node_modules target.
+const KindNodeModules = "node_modules"
+
+// TargetSpec describes a directory to collect: a base name plus optional
+// sibling file globs, at least one of which must exist next to it.
+type TargetSpec struct {
+	Kind     string   // label attached to results, e.g. "next"
+	Name     string   // directory base name, e.g. ".next"
+	Requires []string // sibling globs such as "next.config.*"; empty means always
+}
+
+// DefaultTargets is used when Options.Targets is empty.
+var DefaultTargets = []TargetSpec{{Kind: KindNodeModules, Name: "node_modules"}}
+
+var singleTargets = map[string]TargetSpec{
+	"node_modules": DefaultTargets[0],
+	"next":         {Kind: "next", Name: ".next", Requires: []string{"next.config.*"}},
+	"nuxt":         {Kind: "nuxt", Name: ".nuxt", Requires: []string{"nuxt.config.*"}},
+	"svelte-kit":   {Kind: "svelte-kit", Name: ".svelte-kit", Requires: []string{"svelte.config.*"}},
+	"turbo":        {Kind: "turbo", Name: ".turbo"},
+	"parcel":       {Kind: "parcel", Name: ".parcel-cache"},
+	"dist":         {Kind: "dist", Name: "dist", Requires: []string{"package.json"}},
+	"coverage":     {Kind: "coverage", Name: "coverage", Requires: []string{"package.json"}},
+}
+
+var groupTargets = map[string][]string{
+	"frameworks": {"next", "nuxt", "svelte-kit"},
+	"build":      {"turbo", "parcel", "dist", "coverage"},
+	"js":         {"node_modules", "next", "nuxt", "svelte-kit", "turbo", "parcel", "dist", "coverage"},
+}
+
+// PresetNames lists the accepted preset names, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(singleTargets)+len(groupTargets))
+	for n := range singleTargets {
+		names = append(names, n)
+	}
+	for n := range groupTargets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseTarget resolves a --target value. It accepts a preset name (see
+// PresetNames) or a custom spec "name[:glob[,glob...]]", e.g.
+// ".angular:angular.json".
+func ParseTarget(s string) ([]TargetSpec, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty target")
+	}
+	if spec, ok := singleTargets[s]; ok {
+		return []TargetSpec{spec}, nil
+	}
+	if group, ok := groupTargets[s]; ok {
+		specs := make([]TargetSpec, 0, len(group))
+		for _, n := range group {
+			specs = append(specs, singleTargets[n])
+		}
+		return specs, nil
+	}
+	name, reqs, _ := strings.Cut(s, ":")
+	if name == "" || strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		return nil, fmt.Errorf("invalid target %q: want a preset or a directory name", s)
+	}
+	spec := TargetSpec{Kind: strings.TrimPrefix(name, "."), Name: name}
+	if reqs != "" {
+		for _, r := range strings.Split(reqs, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				if _, err := filepath.Match(r, ""); err != nil {
+					return nil, fmt.Errorf("invalid sibling pattern %q: %w", r, err)
+				}
+				spec.Requires = append(spec.Requires, r)
+			}
+		}
+	}
+	return []TargetSpec{spec}, nil
+}
+
+// targetMatcher indexes target specs by directory name.
+type targetMatcher map[string][]TargetSpec
+
+func newTargetMatcher(specs []TargetSpec) targetMatcher {
+	if len(specs) == 0 {
+		specs = DefaultTargets
+	}
+	m := make(targetMatcher, len(specs))
+	for _, s := range specs {
+		m[s.Name] = append(m[s.Name], s)
+	}
+	return m
+}
+
+// match returns the kind of the first spec satisfied by the directory at path.
+func (m targetMatcher) match(path, name string) (string, bool) {
+	specs, ok := m[name]
+	if !ok {
+		return "", false
+	}
+	var siblings []string
+	for _, s := range specs {
+		if len(s.Requires) == 0 {
+			return s.Kind, true
+		}
+		if siblings == nil {
+			siblings = readDirNames(filepath.Dir(path))
+		}
+		for _, pat := range s.Requires {
+			for _, sib := range siblings {
+				if ok, _ := filepath.Match(pat, sib); ok {
+					return s.Kind, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+func readDirNames(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []string{}
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names
+}
@@ -0,0 +1,375 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Tool is a package that downloads browsers or runtimes into a cache of its
+// own on install.
+type Tool string
+
+const (
+	ToolPlaywright Tool = "playwright" // ~/.cache/ms-playwright/<browser>-<revision>
+	ToolCypress    Tool = "cypress"    // ~/.cache/Cypress/<version>
+	ToolPuppeteer  Tool = "puppeteer"  // ~/.cache/puppeteer/<browser>/<platform>-<build>
+	ToolElectron   Tool = "electron"   // ~/.cache/electron/[<hash>/]electron-v<version>-<platform>.zip
+)
+
+// Tools lists the tools in display order.
+var Tools = []Tool{ToolPlaywright, ToolCypress, ToolPuppeteer, ToolElectron}
+
+// ToolStatus tells whether a scanned project still needs a cached version.
+type ToolStatus string
+
+const (
+	ToolUsed   ToolStatus = "used"
+	ToolUnused ToolStatus = "unused"
+
+	// ToolUnknown marks versions of a tool that a scanned project pins when
+	// the versions it needs could not be worked out, e.g. because the
+	// project's node_modules, which maps Playwright and Puppeteer versions to
+	// browser builds, is gone. They are never offered for deletion.
+	ToolUnknown ToolStatus = "unknown"
+)
+
+// ToolVersion is one cached download of a tool.
+type ToolVersion struct {
+	Tool        Tool
+	Name        string // browser or artifact, e.g. "chromium" or "chromedriver"
+	Version     string // build, revision or package version
+	Path        string
+	Size        int64
+	Reclaimable int64
+	Files       int64
+	Status      ToolStatus
+	UsedBy      []string // project dirs that need this version
+	Err         *ScanError
+}
+
+// key identifies what a project needs from a tool cache.
+func (v ToolVersion) key() string { return v.Name + "@" + v.Version }
+
+// toolCacheDir is where tool keeps its downloads, honouring the tool's
+// environment override.
+func toolCacheDir(tool Tool) string {
+	env := map[Tool]string{
+		ToolPlaywright: "PLAYWRIGHT_BROWSERS_PATH",
+		ToolCypress:    "CYPRESS_CACHE_FOLDER",
+		ToolPuppeteer:  "PUPPETEER_CACHE_DIR",
+		ToolElectron:   "ELECTRON_CACHE",
+	}[tool]
+	if dir := os.Getenv(env); dir != "" && dir != "0" {
+		return dir
+	}
+	if tool == ToolPuppeteer {
+		// puppeteer uses ~/.cache on every platform
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(home, ".cache", "puppeteer")
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	switch tool {
+	case ToolPlaywright:
+		return filepath.Join(cache, "ms-playwright")
+	case ToolCypress:
+		if runtime.GOOS == "windows" {
+			return filepath.Join(cache, "Cypress", "Cache")
+		}
+		return filepath.Join(cache, "Cypress")
+	default:
+		if runtime.GOOS == "windows" {
+			return filepath.Join(cache, "electron", "Cache")
+		}
+		return filepath.Join(cache, "electron")
+	}
+}
+
+var (
+	playwrightDirRe = regexp.MustCompile(`^([a-z_-]+)-(\d+)$`)
+	electronZipRe   = regexp.MustCompile(`^([a-z-]+)-v(\d.*?)-(linux|darwin|win32|mas)-.*\.zip$`)
+)
+
+// listToolVersions lists the cached versions of tool below dir, unsized.
+func listToolVersions(tool Tool, dir string) []ToolVersion {
+	var out []ToolVersion
+	add := func(name, version, path string) {
+		out = append(out, ToolVersion{Tool: tool, Name: name, Version: version, Path: path})
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		switch tool {
+		case ToolPlaywright:
+			if m := playwrightDirRe.FindStringSubmatch(e.Name()); m != nil && e.IsDir() {
+				add(m[1], m[2], path)
+			}
+		case ToolCypress:
+			if e.IsDir() && e.Name()[0] >= '0' && e.Name()[0] <= '9' {
+				add("cypress", e.Name(), path)
+			}
+		case ToolPuppeteer:
+			if !e.IsDir() {
+				continue
+			}
+			builds, _ := os.ReadDir(path)
+			for _, b := range builds {
+				if _, build, ok := strings.Cut(b.Name(), "-"); ok && b.IsDir() {
+					add(e.Name(), build, filepath.Join(path, b.Name()))
+				}
+			}
+		case ToolElectron:
+			// archives sit in the cache root (old) or in one directory per
+			// download URL hash (@electron/get 2+)
+			if m := electronZipRe.FindStringSubmatch(e.Name()); m != nil {
+				add(m[1], m[2], path)
+				continue
+			}
+			if e.IsDir() {
+				sub, _ := os.ReadDir(path)
+				for _, s := range sub {
+					if m := electronZipRe.FindStringSubmatch(s.Name()); m != nil {
+						add(m[1], m[2], filepath.Join(path, s.Name()))
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// toolNeeds is what one project needs from the tool caches.
+type toolNeeds struct {
+	uses  map[Tool]bool     // the project depends on the tool
+	needs map[Tool][]string // keys (name@version) it needs; nil when unknown
+}
+
+// toolPackages are the packages whose presence means a project uses a tool.
+var toolPackages = map[string]Tool{
+	"playwright":       ToolPlaywright,
+	"playwright-core":  ToolPlaywright,
+	"@playwright/test": ToolPlaywright,
+	"cypress":          ToolCypress,
+	"puppeteer":        ToolPuppeteer,
+	"puppeteer-core":   ToolPuppeteer,
+	"electron":         ToolElectron,
+}
+
+// projectToolNeeds reads the versions project pins from its lockfile, with
+// the installed packages as a fallback (bun, no lockfile). Playwright and
+// Puppeteer name their browser builds in files of the installed package.
+func projectToolNeeds(p ProjectInfo) toolNeeds {
+	n := toolNeeds{uses: map[Tool]bool{}, needs: map[Tool][]string{}}
+	var pkgs []lockedPackage
+	switch p.Lockfile {
+	case LockNPM:
+		pkgs = npmLockedPackages(p.LockfilePath)
+	case LockPNPM:
+		pkgs = pnpmLockedPackages(p.LockfilePath)
+	case LockYarn:
+		pkgs = yarnLockedPackages(p.LockfilePath)
+	}
+	nm := filepath.Join(p.Dir, "node_modules")
+	for name := range toolPackages {
+		if v := readPackageVersion(filepath.Join(nm, name)); v != "" {
+			pkgs = append(pkgs, lockedPackage{name: name, version: v})
+		}
+	}
+	for _, pkg := range pkgs {
+		tool, ok := toolPackages[pkg.name]
+		if !ok {
+			continue
+		}
+		n.uses[tool] = true
+		switch pkg.name {
+		case "cypress":
+			n.needs[ToolCypress] = append(n.needs[ToolCypress], "cypress@"+pkg.version)
+		case "electron":
+			// chromedriver and ffmpeg builds share electron's version
+			for _, artifact := range []string{"electron", "chromedriver", "ffmpeg", "mksnapshot"} {
+				n.needs[ToolElectron] = append(n.needs[ToolElectron], artifact+"@"+pkg.version)
+			}
+		}
+	}
+	if n.uses[ToolPlaywright] {
+		n.needs[ToolPlaywright] = playwrightNeeds(nm)
+	}
+	if n.uses[ToolPuppeteer] {
+		n.needs[ToolPuppeteer] = puppeteerNeeds(nm)
+	}
+	return n
+}
+
+// readPackageVersion reads the version of the package installed in dir.
+func readPackageVersion(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var pj packageJSON
+	_ = json.Unmarshal(data, &pj)
+	return pj.Version
+}
+
+// installedCopies finds a package installed in nm: hoisted, nested below
+// another package, or in pnpm's virtual store.
+func installedCopies(nm, name, file string) []string {
+	var out []string
+	for _, pattern := range []string{
+		filepath.Join(nm, name, file),
+		filepath.Join(nm, "*", "node_modules", name, file),
+		filepath.Join(nm, "@*", "*", "node_modules", name, file),
+		filepath.Join(nm, ".pnpm", "*", "node_modules", name, file),
+	} {
+		matches, _ := filepath.Glob(pattern)
+		out = append(out, matches...)
+	}
+	return out
+}
+
+// playwrightNeeds reads the browser revisions from every installed
+// playwright-core's browsers.json; nil when none is installed.
+func playwrightNeeds(nm string) []string {
+	var keys []string
+	for _, path := range installedCopies(nm, "playwright-core", "browsers.json") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var manifest struct {
+			Browsers []struct {
+				Name     string `json:"name"`
+				Revision string `json:"revision"`
+			} `json:"browsers"`
+		}
+		if json.Unmarshal(data, &manifest) != nil {
+			continue
+		}
+		for _, b := range manifest.Browsers {
+			keys = append(keys, strings.ReplaceAll(b.Name, "-", "_")+"@"+b.Revision)
+		}
+	}
+	return keys
+}
+
+var puppeteerRevisionRe = regexp.MustCompile(`['"]?([a-z-]+)['"]?:\s*['"]([^'"]+)['"]`)
+
+// puppeteerNeeds reads the browser builds from every installed
+// puppeteer-core's revisions.js; nil when none is installed.
+func puppeteerNeeds(nm string) []string {
+	var keys []string
+	for _, path := range installedCopies(nm, "puppeteer-core", filepath.Join("lib", "cjs", "puppeteer", "revisions.js")) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, m := range puppeteerRevisionRe.FindAllStringSubmatch(string(data), -1) {
+			keys = append(keys, m[1]+"@"+m[2])
+		}
+	}
+	return keys
+}
+
+// scanToolCaches lists the cached tool versions and cross-references them
+// with what the projects need.
+func scanToolCaches(projects []ProjectInfo) []ToolVersion {
+	var out []ToolVersion
+	for _, tool := range Tools {
+		if dir := toolCacheDir(tool); dir != "" {
+			out = append(out, listToolVersions(tool, dir)...)
+		}
+	}
+	if len(out) == 0 {
+		return out
+	}
+	usedBy := map[Tool]map[string][]string{}
+	unknown := map[Tool]bool{}
+	for _, p := range projects {
+		n := projectToolNeeds(p)
+		for tool := range n.uses {
+			if n.needs[tool] == nil {
+				unknown[tool] = true
+				continue
+			}
+			if usedBy[tool] == nil {
+				usedBy[tool] = map[string][]string{}
+			}
+			for _, key := range n.needs[tool] {
+				usedBy[tool][key] = append(usedBy[tool][key], p.Dir)
+			}
+		}
+	}
+	for i := range out {
+		v := &out[i]
+		v.Status = ToolUnused
+		if users := usedBy[v.Tool][v.key()]; len(users) > 0 {
+			v.Status = ToolUsed
+			v.UsedBy = dedupeSorted(users)
+		} else if unknown[v.Tool] {
+			v.Status = ToolUnknown
+		}
+	}
+	return out
+}
+
+func dedupeSorted(s []string) []string {
+	sort.Strings(s)
+	out := s[:0]
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// measure sizes v like a cache.
+func (v *ToolVersion) measure(ctx context.Context, claims *inodeClaims, opts Options) {
+	u, err := dirSize(ctx, v.Path, false, claims, opts.budget())
+	v.Size, v.Reclaimable, v.Files = u.apparent, u.reclaimable, u.files
+	if err != nil {
+		v.Err = newScanError(OpSize, v.Path, err)
+	}
+}
+
+// PlanToolPrune plans the removal of the cached tool versions no scanned
+// project needs. versions and projects normally come from ScanCaches. Like
+// PlanPrune it only prunes a tool some scanned project uses: the versions of
+// other tools are kept, and when no project uses any of them it returns
+// ErrNoReferences.
+func PlanToolPrune(versions []ToolVersion, projects []ProjectInfo) (PrunePlan, error) {
+	plan := PrunePlan{Action: "remove tool versions no scanned project needs"}
+	used := map[Tool]bool{}
+	for _, p := range projects {
+		for tool := range projectToolNeeds(p).uses {
+			used[tool] = true
+		}
+	}
+	referenced := false
+	for _, v := range versions {
+		referenced = referenced || used[v.Tool]
+	}
+	if !referenced {
+		return plan, ErrNoReferences
+	}
+	for _, v := range versions {
+		if v.Status != ToolUnused || !used[v.Tool] {
+			plan.Kept++
+			continue
+		}
+		plan.Entries = append(plan.Entries, PruneEntry{Path: v.Path, Size: v.Size, Files: v.Files})
+		plan.Size += v.Size
+	}
+	return plan, nil
+}
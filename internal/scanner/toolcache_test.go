@@ -0,0 +1,139 @@
+package scanner
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestScanCaches_Tools(t *testing.T) {
+	home := isolateCaches(t)
+	// the default locations differ per platform; point every tool at home
+	t.Setenv("PLAYWRIGHT_BROWSERS_PATH", filepath.Join(home, "ms-playwright"))
+	t.Setenv("CYPRESS_CACHE_FOLDER", filepath.Join(home, "Cypress"))
+	t.Setenv("PUPPETEER_CACHE_DIR", filepath.Join(home, "puppeteer"))
+	t.Setenv("ELECTRON_CACHE", filepath.Join(home, "electron"))
+	writeFiles(t, home, map[string]string{
+		"ms-playwright/chromium-1091/chrome":               "new",
+		"ms-playwright/chromium-1000/chrome":               "old",
+		"ms-playwright/chromium_headless_shell-1091/shell": "new",
+		"ms-playwright/.links/abc":                         "/somewhere",
+		"Cypress/13.6.0/Cypress/cypress":                   "new",
+		"Cypress/12.0.0/Cypress/cypress":                   "old",
+		"puppeteer/chrome/linux-121.0.6167.85/chrome":      "x",
+		"electron/4f2a/electron-v28.1.0-linux-x64.zip":     "new",
+		"electron/electron-v9.0.0-darwin-x64.zip":          "old",
+		"electron/4f2a/SHASUMS256.txt":                     "",
+	})
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/package.json": `{"name": "app"}`,
+		"app/package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "app"},
+			"node_modules/cypress": {"version": "13.6.0"},
+			"node_modules/electron": {"version": "28.1.0"},
+			"node_modules/playwright-core": {"version": "1.40.0"}}}`,
+		"app/node_modules/playwright-core/package.json": `{"name": "playwright-core", "version": "1.40.0"}`,
+		"app/node_modules/playwright-core/browsers.json": `{"browsers": [
+			{"name": "chromium", "revision": "1091"},
+			{"name": "chromium-headless-shell", "revision": "1091"},
+			{"name": "firefox", "revision": "1429"}]}`,
+		// pins puppeteer, but without node_modules its chrome build is unknown
+		"old/package.json":  `{"name": "old"}`,
+		"old/yarn.lock":     "# yarn lockfile v1\n\n\npuppeteer@^21.0.0:\n  version \"21.6.0\"\n",
+		"old/.yarn/cache/x": "",
+	})
+
+	rep, errs := ScanCaches(nil, root, Options{})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	var got []string
+	for _, v := range rep.Tools {
+		got = append(got, string(v.Tool)+" "+v.key()+" "+string(v.Status))
+		if v.Size == 0 || v.Err != nil {
+			t.Errorf("%s: not measured: %+v", v.Path, v)
+		}
+		if v.Status == ToolUsed && (len(v.UsedBy) != 1 || filepath.Base(v.UsedBy[0]) != "app") {
+			t.Errorf("%s: used by %v, want app", v.Path, v.UsedBy)
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		"cypress cypress@12.0.0 unused",
+		"cypress cypress@13.6.0 used",
+		"electron electron@28.1.0 used",
+		"electron electron@9.0.0 unused",
+		"playwright chromium@1000 unused",
+		"playwright chromium@1091 used",
+		"playwright chromium_headless_shell@1091 used",
+		"puppeteer chrome@121.0.6167.85 unknown",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("tools:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	plan, err := PlanToolPrune(rep.Tools, rep.Projects)
+	if err != nil || len(plan.Entries) != 3 || plan.Kept != 5 {
+		t.Fatalf("expected 3 unused versions and 5 kept, got %+v (%v)", plan, err)
+	}
+	if _, err := PlanToolPrune(rep.Tools, nil); !errors.Is(err, ErrNoReferences) {
+		t.Fatalf("expected ErrNoReferences without projects, got %v", err)
+	}
+}
+
+func TestPlanToolPrune_OnlyToolsInUse(t *testing.T) {
+	home := isolateCaches(t)
+	t.Setenv("CYPRESS_CACHE_FOLDER", filepath.Join(home, "Cypress"))
+	t.Setenv("ELECTRON_CACHE", filepath.Join(home, "electron"))
+	writeFiles(t, home, map[string]string{
+		"Cypress/13.6.0/Cypress/cypress":         "new",
+		"Cypress/12.0.0/Cypress/cypress":         "old",
+		"electron/electron-v9.0.0-linux-x64.zip": "other projects",
+	})
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/package.json": `{"name": "app"}`,
+		"app/package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "app"},
+			"node_modules/cypress": {"version": "13.6.0"}}}`,
+		"app/node_modules/cypress/package.json": `{"name": "cypress", "version": "13.6.0"}`,
+		"web/package.json":                      `{"name": "web"}`,
+		"web/package-lock.json":                 `{"lockfileVersion": 3, "packages": {"": {"name": "web"}}}`,
+		"web/node_modules/.package-lock.json":   `{"packages": {}}`,
+	})
+	rep, errs := ScanCaches(nil, root, Options{})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	// electron is unused by every scanned project, so none of it is pruned
+	plan, err := PlanToolPrune(rep.Tools, rep.Projects)
+	if err != nil || len(plan.Entries) != 1 || plan.Kept != 2 {
+		t.Fatalf("expected 1 unused cypress version and 2 kept, got %+v (%v)", plan, err)
+	}
+	if got := filepath.Base(plan.Entries[0].Path); got != "12.0.0" {
+		t.Fatalf("pruned %s, want cypress 12.0.0", plan.Entries[0].Path)
+	}
+
+	var electron []ToolVersion
+	var web []ProjectInfo
+	for _, v := range rep.Tools {
+		if v.Tool == ToolElectron {
+			electron = append(electron, v)
+		}
+	}
+	for _, p := range rep.Projects {
+		if p.Name == "web" {
+			web = append(web, p)
+		}
+	}
+	if _, err := PlanToolPrune(electron, rep.Projects); !errors.Is(err, ErrNoReferences) {
+		t.Fatalf("expected ErrNoReferences for electron alone, got %v", err)
+	}
+	if _, err := PlanToolPrune(rep.Tools, web); !errors.Is(err, ErrNoReferences) {
+		t.Fatalf("expected ErrNoReferences for a project using no tool, got %v", err)
+	}
+}
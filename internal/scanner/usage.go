@@ -0,0 +1,66 @@
+package scanner
+
+import "sync"
+
+// usage is the disk usage of a directory tree.
+type usage struct {
+	apparent    int64 // sum of file sizes as reported by stat
+	reclaimable int64 // allocated bytes freed by deleting the tree
+	files       int64 // entries that are neither directories nor symlinks
+	dirs        int64 // directories, the root included
+	symlinks    int64
+	partial     bool // a budget ran out; the figures are lower bounds
+
+	// linked are the multiply-linked inodes counted in reclaimable, for the
+	// size index.
+	linked []linkedInode
+}
+
+// linkedInode is a multiply-linked inode claimed by a measurement.
+type linkedInode struct {
+	Dev       uint64 `json:"dev"`
+	Ino       uint64 `json:"ino"`
+	Allocated int64  `json:"allocated"`
+}
+
+// fileID identifies an inode on a device.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// fileStat is the platform-specific part of a file's metadata.
+type fileStat struct {
+	id        fileID
+	allocated int64 // bytes of allocated blocks
+	nlink     uint64
+}
+
+// linkCount tracks how many links of a multiply-linked inode were found.
+type linkCount struct {
+	nlink     uint64
+	seen      uint64
+	allocated int64
+}
+
+// inodeClaims records which inodes have already been attributed to a result.
+// It is shared by the size workers of a scan.
+type inodeClaims struct {
+	mu sync.Mutex
+	m  map[fileID]struct{}
+}
+
+func newInodeClaims() *inodeClaims {
+	return &inodeClaims{m: make(map[fileID]struct{})}
+}
+
+// claim reports whether id was unclaimed, marking it claimed.
+func (c *inodeClaims) claim(id fileID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.m[id]; ok {
+		return false
+	}
+	c.m[id] = struct{}{}
+	return true
+}
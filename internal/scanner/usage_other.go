@@ -0,0 +1,10 @@
+//go:build !unix
+
+package scanner
+
+import "io/fs"
+
+// statOf is unavailable on this platform; callers fall back to apparent sizes.
+func statOf(info fs.FileInfo) (fileStat, bool) {
+	return fileStat{}, false
+}
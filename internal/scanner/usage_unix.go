@@ -0,0 +1,22 @@
+//go:build unix
+
+package scanner
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// statOf extracts inode identity, link count and allocated blocks.
+// st_blocks is always expressed in 512-byte units.
+func statOf(info fs.FileInfo) (fileStat, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileStat{}, false
+	}
+	return fileStat{
+		id:        fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)},
+		allocated: int64(st.Blocks) * 512,
+		nlink:     uint64(st.Nlink),
+	}, true
+}
@@ -0,0 +1,148 @@
+package scanner
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// discoverer applies the discovery policy (excludes, targets, MaxDepth, never
+// descending into node_modules) to the entries of one scan. It is safe for
+// concurrent use by the parallel walker.
+type discoverer struct {
+	opts      Options
+	rootDepth int
+	targets   targetMatcher
+	ignore    *ignoreSet
+	found     func(candidate)              // called for every target; may run concurrently
+	failed    func(path string, err error) // called for unreadable entries; may run concurrently
+}
+
+func newDiscoverer(root string, opts Options, found func(candidate), failed func(string, error)) *discoverer {
+	return &discoverer{
+		opts:      opts,
+		rootDepth: depthOf(root),
+		targets:   newTargetMatcher(opts.Targets),
+		ignore:    newIgnoreSet(root, opts),
+		found:     found,
+		failed:    failed,
+	}
+}
+
+// visit handles one entry and reports whether the walk should descend into it.
+// Symlinked directories are never descended into during discovery; the
+// symlink policy only affects size calculation.
+func (d *discoverer) visit(path string, e fs.DirEntry) bool {
+	if !e.IsDir() {
+		return false
+	}
+	if d.ignore.excluded(path, true) {
+		return false
+	}
+	// Capture targets regardless of depth gating (we don't descend into them)
+	name := e.Name()
+	if kind, ok := d.targets.match(path, name); ok {
+		d.found(candidate{path: path, kind: kind})
+		return false
+	}
+	if name == "node_modules" {
+		return false
+	}
+	if d.opts.MaxDepth >= 0 && depthOf(path)-d.rootDepth > d.opts.MaxDepth {
+		return false
+	}
+	d.ignore.enter(path)
+	return true
+}
+
+// discover walks root with up to opts.Concurrency directory readers, calling
+// found for every target directory and failed for every read error.
+func discover(ctx context.Context, root string, opts Options, found func(candidate), failed func(string, error)) {
+	d := newDiscoverer(root, opts, found, failed)
+	info, err := os.Lstat(root)
+	if err != nil {
+		d.failed(root, err)
+		return
+	}
+	if !d.visit(root, fs.FileInfoToDirEntry(info)) {
+		return
+	}
+	n := opts.Concurrency
+	if n < 1 {
+		n = 1
+	}
+	w := &parallelWalker{ctx: ctx, d: d, sem: make(chan struct{}, n-1)}
+	w.dir(root)
+	w.wg.Wait()
+}
+
+// FindNodeModules walks root like a scan and returns the node_modules
+// directories it finds, sorted, without measuring them or reading their
+// projects, e.g. to index their packages. It also returns the directories
+// the walk could not read, nil when there are none. opts.Targets is ignored.
+func FindNodeModules(ctx context.Context, root string, opts Options) ([]string, []*ScanError) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	opts = opts.withDefaults()
+	opts.Targets = DefaultTargets
+	var mu sync.Mutex
+	var dirs []string
+	var walkErrs []*ScanError
+	discover(ctx, root, opts, func(c candidate) {
+		mu.Lock()
+		dirs = append(dirs, c.path)
+		mu.Unlock()
+	}, func(path string, err error) {
+		mu.Lock()
+		walkErrs = append(walkErrs, newScanError(OpWalk, path, err))
+		mu.Unlock()
+	})
+	sort.Strings(dirs)
+	return dirs, walkErrs
+}
+
+// parallelWalker reads directories concurrently. A subdirectory is handed to
+// a new goroutine when a slot is free and walked inline otherwise, so the
+// number of goroutines stays bounded without risking a deadlock.
+type parallelWalker struct {
+	ctx context.Context
+	d   *discoverer
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+func (w *parallelWalker) dir(path string) {
+	if w.ctx.Err() != nil {
+		return
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		w.d.failed(path, err)
+	}
+	for _, e := range entries {
+		if w.ctx.Err() != nil {
+			return
+		}
+		child := filepath.Join(path, e.Name())
+		if !w.d.visit(child, e) {
+			continue
+		}
+		select {
+		case w.sem <- struct{}{}:
+			w.wg.Add(1)
+			go func() {
+				defer func() {
+					<-w.sem
+					w.wg.Done()
+				}()
+				w.dir(child)
+			}()
+		default:
+			w.dir(child)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// walkSequential is the single filepath.WalkDir discovery that discover
+// replaced, kept as the reference for equivalence tests and benchmarks.
+func walkSequential(root string, opts Options, found func(candidate), failed func(string, error)) {
+	d := newDiscoverer(root, opts, found, failed)
+	_ = filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			d.failed(path, err)
+			return nil
+		}
+		if !d.visit(path, e) && e.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// makeFixtureTree builds a tree of width^depth project dirs, each with a
+// src/ folder and every other one with a node_modules.
+func makeFixtureTree(tb testing.TB, root string, width, depth int) {
+	tb.Helper()
+	var build func(dir string, level int)
+	build = func(dir string, level int) {
+		if level == depth {
+			return
+		}
+		for i := 0; i < width; i++ {
+			p := filepath.Join(dir, fmt.Sprintf("p%d", i))
+			if err := os.MkdirAll(filepath.Join(p, "src"), 0o755); err != nil {
+				tb.Fatalf("mkdir: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(p, "src", "index.js"), nil, 0o644); err != nil {
+				tb.Fatalf("write: %v", err)
+			}
+			if i%2 == 0 {
+				if err := os.MkdirAll(filepath.Join(p, "node_modules", "dep", "node_modules", "inner"), 0o755); err != nil {
+					tb.Fatalf("mkdir: %v", err)
+				}
+			}
+			build(p, level+1)
+		}
+	}
+	build(root, 0)
+}
+
+func collect(walk func(string, Options, func(candidate), func(string, error)), root string, opts Options) []string {
+	var mu sync.Mutex
+	var paths []string
+	walk(root, opts.withDefaults(), func(c candidate) {
+		mu.Lock()
+		paths = append(paths, c.path)
+		mu.Unlock()
+	}, func(string, error) {})
+	sort.Strings(paths)
+	return paths
+}
+
+func walkParallel(root string, opts Options, found func(candidate), failed func(string, error)) {
+	discover(context.Background(), root, opts, found, failed)
+}
+
+func TestDiscover_MatchesSequentialWalk(t *testing.T) {
+	root := t.TempDir()
+	makeFixtureTree(t, root, 3, 3)
+	if runtime.GOOS != "windows" {
+		// discovery never follows symlinked directories
+		if err := os.Symlink(filepath.Join(root, "p0"), filepath.Join(root, "link")); err != nil {
+			t.Fatalf("symlink: %v", err)
+		}
+	}
+	for _, opts := range []Options{
+		{},
+		{MaxDepth: 1},
+		{Excludes: []string{"p1/"}},
+		{Concurrency: 1},
+	} {
+		want := collect(walkSequential, root, opts)
+		got := collect(walkParallel, root, opts)
+		if len(want) == 0 {
+			t.Fatalf("fixture produced no candidates for %+v", opts)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("opts %+v: parallel found %d, sequential %d\n%v\n%v", opts, len(got), len(want), got, want)
+		}
+	}
+}
+
+func TestFindNodeModules(t *testing.T) {
+	root := t.TempDir()
+	makeFixtureTree(t, root, 2, 2)
+	got, errs := FindNodeModules(nil, root, Options{Targets: []TargetSpec{{Kind: "dist", Name: "dist"}}})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	want := collect(walkSequential, root, Options{})
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("found %v, want %v", got, want)
+	}
+}
+
+func TestFindNodeModules_NegationReincludesUnderDirGlob(t *testing.T) {
+	root := t.TempDir()
+	for _, d := range []string{"dir/keep/node_modules", "dir/drop/node_modules", "app/node_modules"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.FromSlash(d)), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	got, errs := FindNodeModules(nil, root, Options{Excludes: []string{"dir/**", "!dir/keep"}})
+	if errs != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	want := []string{
+		filepath.Join(root, "app", "node_modules"),
+		filepath.Join(root, "dir", "keep", "node_modules"),
+	}
+	sort.Strings(got)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("found %v, want %v", got, want)
+	}
+}
+
+func BenchmarkDiscover(b *testing.B) {
+	root := b.TempDir()
+	makeFixtureTree(b, root, 6, 4)
+	opts := Options{}.withDefaults()
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			walkSequential(root, opts, func(candidate) {}, func(string, error) {})
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			discover(context.Background(), root, opts, func(candidate) {}, func(string, error) {})
+		}
+	})
+}
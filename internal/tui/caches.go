@@ -0,0 +1,363 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"node-module-man/internal/deleter"
+	"node-module-man/internal/journal"
+	"node-module-man/internal/scanner"
+	"node-module-man/pkg/utils"
+)
+
+type cachesDoneMsg struct {
+	rep  scanner.CacheReport
+	errs []*scanner.ScanError
+}
+
+type prunePlanMsg struct {
+	plan scanner.PrunePlan
+	err  error
+}
+
+type pruneDoneMsg struct {
+	path       string // the pruned cache; "" for tool versions and runtimes
+	what       string // what was pruned, for display
+	sum        deleter.Summary
+	measured   *scanner.Cache // the cache measured again; nil on a dry run
+	journalErr error
+}
+
+// startCaches lists the package manager caches and the projects below the
+// scan root that pruning keeps packages for.
+func (m model) startCaches() (tea.Model, tea.Cmd) {
+	m.st = statusCaches
+	m.cacheLoading = true
+	m.cacheReport = scanner.CacheReport{}
+	m.cacheErrs = nil
+	m.cacheCursor, m.cacheScroll = 0, 0
+	m.cachePlan, m.cachePlanErr, m.cachePlanFor, m.cacheBusy, m.cacheMsg = nil, nil, "", "", ""
+	path, opts := m.path, m.opts
+	return m, func() tea.Msg {
+		rep, errs := scanner.ScanCaches(context.Background(), path, opts)
+		return cachesDoneMsg{rep: rep, errs: errs}
+	}
+}
+
+func (m model) handleCachesDone(msg cachesDoneMsg) (tea.Model, tea.Cmd) {
+	if m.st != statusCaches || !m.cacheLoading {
+		return m, nil
+	}
+	m.cacheLoading = false
+	m.cacheReport, m.cacheErrs = msg.rep, msg.errs
+	return m, nil
+}
+
+func (m model) handlePrunePlan(msg prunePlanMsg) (tea.Model, tea.Cmd) {
+	if m.st != statusCaches || m.cacheBusy == "" {
+		return m, nil
+	}
+	m.cacheBusy = ""
+	m.cachePlan, m.cachePlanErr, m.cachePlanFor = &msg.plan, msg.err, m.displayPath(msg.plan.Cache.Path)
+	return m, nil
+}
+
+func (m model) handlePruneDone(msg pruneDoneMsg) (tea.Model, tea.Cmd) {
+	if m.st != statusCaches {
+		return m, nil
+	}
+	m.cacheBusy = ""
+	verb := "Pruned"
+	if m.dryRun {
+		verb = "Dry run: would prune"
+	}
+	m.cacheMsg = fmt.Sprintf("%s %d entries, %s from %s", verb, len(msg.sum.Successes), utils.HumanizeBytes(msg.sum.Freed), msg.what)
+	if n := len(msg.sum.Failures); n > 0 {
+		m.cacheMsg += fmt.Sprintf("; %d failed (first: %v)", n, msg.sum.Failures[0].Err)
+	}
+	if msg.journalErr != nil {
+		m.cacheMsg += fmt.Sprintf("; failed to update the operations journal: %v", msg.journalErr)
+	}
+	for i, c := range m.cacheReport.Caches {
+		if c.Path == msg.path && msg.measured != nil {
+			m.cacheReport.Caches[i] = *msg.measured
+		}
+	}
+	if msg.path == "" && !m.dryRun {
+		// tool versions and runtimes are removed whole: drop the deleted rows
+		gone := map[string]bool{}
+		for _, t := range msg.sum.Successes {
+			gone[t.Path] = true
+		}
+		tools := m.cacheReport.Tools[:0]
+		for _, v := range m.cacheReport.Tools {
+			if !gone[v.Path] {
+				tools = append(tools, v)
+			}
+		}
+		runtimes := m.cacheReport.Runtimes[:0]
+		for _, rt := range m.cacheReport.Runtimes {
+			if !gone[rt.Path] {
+				runtimes = append(runtimes, rt)
+			}
+		}
+		m.cacheReport.Tools, m.cacheReport.Runtimes = tools, runtimes
+		if n := len(m.cacheReport.Caches) + len(tools) + len(runtimes); m.cacheCursor >= n && n > 0 {
+			m.cacheCursor = n - 1
+		}
+	}
+	return m, nil
+}
+
+func (m model) updateCaches(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	caches, tools, runtimes := m.cacheReport.Caches, m.cacheReport.Tools, m.cacheReport.Runtimes
+	n := len(caches) + len(tools) + len(runtimes) // tool versions and runtimes are listed below the caches
+	if m.cachePlan != nil {
+		// a plan is on screen: confirm or dismiss it
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "y":
+			plan, what := *m.cachePlan, m.cachePlanFor
+			m.cachePlan, m.cachePlanErr = nil, nil
+			if len(plan.Entries) == 0 {
+				return m, nil
+			}
+			m.cacheBusy = "pruning " + what
+			targets := make([]deleter.Target, 0, len(plan.Entries))
+			for _, e := range plan.Entries {
+				targets = append(targets, deleter.Target{Path: e.Path, Size: e.Size, Files: e.Files})
+			}
+			opts, dryRun := m.opts, m.dryRun
+			return m, func() tea.Msg {
+				done := pruneDoneMsg{path: plan.Cache.Path, what: what}
+				done.sum = deleter.DeleteTargets(context.Background(), targets, deleter.Options{Concurrency: opts.Concurrency, DryRun: dryRun}, nil)
+				if !dryRun {
+					done.journalErr = journal.Journal{}.Record(journal.FromDelete(done.sum, journal.OpDelete)...)
+				}
+				if !dryRun && plan.Cache.Path != "" {
+					// measure again so the list shows what is left
+					c := scanner.MeasureCache(context.Background(), plan.Cache, opts)
+					done.measured = &c
+				}
+				return done
+			}
+		default:
+			m.cachePlan, m.cachePlanErr = nil, nil
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", "h", "left", "backspace":
+		if m.cacheBusy != "" {
+			return m, nil // let a running prune finish
+		}
+		m.st = statusReady
+		m.cacheLoading = false
+		return m, nil
+	case "?":
+		m.showHelp = !m.showHelp
+	case "up", "k":
+		if m.cacheCursor > 0 {
+			m.cacheCursor--
+		}
+	case "down", "j":
+		if m.cacheCursor < n-1 {
+			m.cacheCursor++
+		}
+	case "home", "g":
+		m.cacheCursor = 0
+	case "end", "G":
+		if n > 0 {
+			m.cacheCursor = n - 1
+		}
+	case "p", "d":
+		if m.cacheLoading || m.cacheBusy != "" || m.cacheCursor >= n {
+			return m, nil
+		}
+		projects := m.cacheReport.Projects
+		m.cacheMsg = ""
+		if i := m.cacheCursor - len(caches) - len(tools); i >= 0 {
+			// a runtime: offered only when no project requests it
+			plan, err := scanner.PlanRuntimePrune(runtimes[i:i+1], projects)
+			m.cachePlan, m.cachePlanErr, m.cachePlanFor = &plan, err, "Node versions"
+			return m, nil
+		}
+		if i := m.cacheCursor - len(caches); i >= 0 {
+			// a tool version: offered only when no project needs it
+			plan, err := scanner.PlanToolPrune(tools[i:i+1], projects)
+			m.cachePlan, m.cachePlanErr, m.cachePlanFor = &plan, err, "tool caches"
+			return m, nil
+		}
+		c := caches[m.cacheCursor]
+		m.cacheBusy = "planning " + m.displayPath(c.Path)
+		return m, func() tea.Msg {
+			plan, err := scanner.PlanPrune(context.Background(), c, projects)
+			return prunePlanMsg{plan: plan, err: err}
+		}
+	case "U":
+		if m.cacheLoading || m.cacheBusy != "" || len(tools) == 0 {
+			return m, nil
+		}
+		m.cacheMsg = ""
+		plan, err := scanner.PlanToolPrune(tools, m.cacheReport.Projects)
+		m.cachePlan, m.cachePlanErr, m.cachePlanFor = &plan, err, "tool caches"
+		return m, nil
+	case "N":
+		if m.cacheLoading || m.cacheBusy != "" || len(runtimes) == 0 {
+			return m, nil
+		}
+		m.cacheMsg = ""
+		plan, err := scanner.PlanRuntimePrune(runtimes, m.cacheReport.Projects)
+		m.cachePlan, m.cachePlanErr, m.cachePlanFor = &plan, err, "Node versions"
+		return m, nil
+	}
+	h := m.inspectHeight()
+	if m.cacheCursor >= m.cacheScroll+h {
+		m.cacheScroll = m.cacheCursor - h + 1
+	}
+	if m.cacheCursor < m.cacheScroll {
+		m.cacheScroll = m.cacheCursor
+	}
+	return m, nil
+}
+
+func (m *model) cachesView() string {
+	var b strings.Builder
+	if m.cacheLoading {
+		b.WriteString(fmt.Sprintf("Package manager caches: scanning... %s\nPress esc to go back.\n", m.sp.View()))
+		return b.String()
+	}
+	rep := m.cacheReport
+	var size, reclaimable int64
+	for _, c := range rep.Caches {
+		size += c.Size
+		reclaimable += c.Reclaimable
+	}
+	b.WriteString(fmt.Sprintf("Package manager caches: %d  Reclaimable: %s  Apparent: %s  Projects scanned: %d\n",
+		len(rep.Caches), utils.HumanizeBytes(reclaimable), utils.HumanizeBytes(size), len(rep.Projects)))
+	var unused int64
+	for _, v := range rep.Tools {
+		if v.Status == scanner.ToolUnused {
+			unused += v.Reclaimable
+		}
+	}
+	if len(rep.Tools) > 0 {
+		b.WriteString(fmt.Sprintf("Tool downloads: %d versions  Unused: %s\n", len(rep.Tools), utils.HumanizeBytes(unused)))
+	}
+	if len(rep.Runtimes) > 0 {
+		var unusedNode int64
+		for _, rt := range rep.Runtimes {
+			if rt.Status == scanner.ToolUnused && !rt.Default {
+				unusedNode += rt.Reclaimable
+			}
+		}
+		b.WriteString(fmt.Sprintf("Node runtimes: %d versions  Unused: %s\n", len(rep.Runtimes), utils.HumanizeBytes(unusedNode)))
+	}
+	b.WriteString("Keys: ↑↓ move, p prune, U remove unused tool versions, N remove unused Node versions, esc/h back, ? help\n")
+	if len(m.cacheErrs) > 0 {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("%d directories could not be read while looking for projects", len(m.cacheErrs))) + "\n")
+	}
+	switch {
+	case m.cacheBusy != "":
+		b.WriteString(fmt.Sprintf("%s %s\n", m.sp.View(), m.cacheBusy))
+	case m.cachePlan != nil:
+		b.WriteString(m.prunePlanText())
+	case m.cacheMsg != "":
+		b.WriteString(m.cacheMsg + "\n")
+	}
+	b.WriteString("\n")
+	if len(rep.Caches)+len(rep.Tools)+len(rep.Runtimes) == 0 {
+		b.WriteString("No package manager caches found.\n")
+		return b.String()
+	}
+	rows := len(rep.Caches) + len(rep.Tools) + len(rep.Runtimes)
+	end := m.cacheScroll + m.inspectHeight()
+	if end > rows {
+		end = rows
+	}
+	for i := m.cacheScroll; i < end; i++ {
+		prefix := "  "
+		if i == m.cacheCursor {
+			prefix = cursorStyle.Render(">") + " "
+		}
+		if r := i - len(rep.Caches) - len(rep.Tools); r >= 0 {
+			b.WriteString(m.runtimeLine(prefix, rep.Runtimes[r]) + "\n")
+			continue
+		}
+		if t := i - len(rep.Caches); t >= 0 {
+			b.WriteString(m.toolLine(prefix, rep.Tools[t]) + "\n")
+			continue
+		}
+		c := rep.Caches[i]
+		size := fmt.Sprintf("%8s", utils.HumanizeBytesCompact(c.Reclaimable))
+		if c.Partial {
+			size = "≥" + strings.TrimLeft(size, " ")
+		}
+		line := fmt.Sprintf("%s%s %7s  %-10s %s", prefix, sizeColorStyle(c.Reclaimable).Render(size), utils.HumanizeCount(c.Files), c.Kind, m.displayPath(c.Path))
+		if c.Err != nil {
+			line += " " + errorStyle.Render(fmt.Sprintf("(%s error)", c.Err.Kind))
+		}
+		b.WriteString(line + "\n")
+	}
+	if m.showHelp {
+		b.WriteString("\n" + m.helpText())
+	}
+	return b.String()
+}
+
+// toolLine renders a cached tool version row.
+func (m *model) toolLine(prefix string, v scanner.ToolVersion) string {
+	size := fmt.Sprintf("%8s", utils.HumanizeBytesCompact(v.Reclaimable))
+	status := string(v.Status)
+	if v.Status == scanner.ToolUsed {
+		status = fmt.Sprintf("used by %d", len(v.UsedBy))
+	}
+	line := fmt.Sprintf("%s%s %-9s  %-10s %s@%s  %s", prefix, sizeColorStyle(v.Reclaimable).Render(size), status, v.Tool, v.Name, v.Version, m.displayPath(v.Path))
+	if v.Err != nil {
+		line += " " + errorStyle.Render(fmt.Sprintf("(%s error)", v.Err.Kind))
+	}
+	return line
+}
+
+// runtimeLine renders an installed Node runtime row.
+func (m *model) runtimeLine(prefix string, rt scanner.NodeRuntime) string {
+	size := fmt.Sprintf("%8s", utils.HumanizeBytesCompact(rt.Reclaimable))
+	status := string(rt.Status)
+	switch {
+	case rt.Default:
+		status = "default"
+	case rt.Status == scanner.ToolUsed:
+		status = fmt.Sprintf("used by %d", len(rt.UsedBy))
+	}
+	line := fmt.Sprintf("%s%s %-9s  %-10s node@%s  %s", prefix, sizeColorStyle(rt.Reclaimable).Render(size), status, rt.Manager, rt.Version, m.displayPath(rt.Path))
+	if len(rt.Globals) > 0 {
+		line += pendingStyle.Render(fmt.Sprintf("  globals: %s", strings.Join(rt.Globals, ", ")))
+	}
+	if rt.Err != nil {
+		line += " " + errorStyle.Render(fmt.Sprintf("(%s error)", rt.Err.Kind))
+	}
+	return line
+}
+
+// prunePlanText describes the plan awaiting confirmation.
+func (m *model) prunePlanText() string {
+	p := m.cachePlan
+	if m.cachePlanErr != nil {
+		return errorStyle.Render(fmt.Sprintf("Cannot prune %s: %v", m.cachePlanFor, m.cachePlanErr)) + "\nPress any key to continue.\n"
+	}
+	if len(p.Entries) == 0 {
+		return fmt.Sprintf("Nothing to prune in %s (%d entries still referenced). Press any key to continue.\n", m.cachePlanFor, p.Kept)
+	}
+	mode := ""
+	if m.dryRun {
+		mode = " (dry run)"
+	}
+	return fmt.Sprintf("Prune %s: %s.\nRemoves %d entries, ~%s; keeps %d.%s Press y to confirm, any other key to cancel.\n",
+		m.cachePlanFor, p.Action, len(p.Entries), utils.HumanizeBytes(p.Size), p.Kept, mode)
+}
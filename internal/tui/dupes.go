@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"node-module-man/internal/scanner"
+	"node-module-man/pkg/utils"
+)
+
+type dupesDoneMsg struct {
+	rep  scanner.DuplicateReport
+	errs []*scanner.ScanError
+}
+
+// startDupes builds the duplicate-package report over the node_modules
+// items in the current view.
+func (m model) startDupes() (tea.Model, tea.Cmd) {
+	var dirs []string
+	for _, idx := range m.viewIndexes() {
+		if m.items[idx].kind == scanner.KindNodeModules {
+			dirs = append(dirs, m.items[idx].path)
+		}
+	}
+	m.st = statusDupes
+	m.dupLoading = true
+	m.dupReport = scanner.DuplicateReport{}
+	m.dupErrs = nil
+	m.dupCursor, m.dupScroll, m.dupOpen = 0, 0, -1
+	opts := m.opts
+	return m, func() tea.Msg {
+		rep, errs := scanner.FindDuplicates(context.Background(), dirs, opts)
+		return dupesDoneMsg{rep: rep, errs: errs}
+	}
+}
+
+func (m model) handleDupesDone(msg dupesDoneMsg) (tea.Model, tea.Cmd) {
+	if m.st != statusDupes || !m.dupLoading {
+		return m, nil
+	}
+	m.dupLoading = false
+	m.dupReport, m.dupErrs = msg.rep, msg.errs
+	return m, nil
+}
+
+func (m model) updateDupes(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	n := len(m.dupReport.Duplicates)
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", "h", "left", "backspace":
+		m.st = statusReady
+		m.dupLoading = false
+		return m, nil
+	case "?":
+		m.showHelp = !m.showHelp
+	case "up", "k":
+		if m.dupCursor > 0 {
+			m.dupCursor--
+		}
+	case "down", "j":
+		if m.dupCursor < n-1 {
+			m.dupCursor++
+		}
+	case "home", "g":
+		m.dupCursor = 0
+	case "end", "G":
+		if n > 0 {
+			m.dupCursor = n - 1
+		}
+	case "enter", "l", "right", " ":
+		if m.dupOpen == m.dupCursor {
+			m.dupOpen = -1
+		} else {
+			m.dupOpen = m.dupCursor
+		}
+	}
+	// keep the cursor row (not its expanded copies) in view
+	h := m.inspectHeight()
+	if m.dupCursor >= m.dupScroll+h {
+		m.dupScroll = m.dupCursor - h + 1
+	}
+	if m.dupCursor < m.dupScroll {
+		m.dupScroll = m.dupCursor
+	}
+	return m, nil
+}
+
+func (m *model) dupesView() string {
+	var b strings.Builder
+	if m.dupLoading {
+		b.WriteString(fmt.Sprintf("Duplicate packages: indexing node_modules... %s\nPress esc to go back.\n", m.sp.View()))
+		return b.String()
+	}
+	rep := m.dupReport
+	b.WriteString(fmt.Sprintf("Duplicate packages: %d of %d installs  Wasted: %s  Shared store would save: ~%s\n",
+		len(rep.Duplicates), rep.Packages, utils.HumanizeBytes(rep.Wasted), utils.HumanizeBytes(rep.StoreSavings)))
+	b.WriteString("Keys: ↑↓ move, enter/l show copies, esc/h back, ? help\n")
+	if len(m.dupErrs) > 0 {
+		b.WriteString(m.errorsSummary(m.dupErrs) + "\n")
+	}
+	b.WriteString("\n")
+	if len(rep.Duplicates) == 0 {
+		b.WriteString("No duplicated packages found.\n")
+		return b.String()
+	}
+	end := m.dupScroll + m.inspectHeight()
+	if end > len(rep.Duplicates) {
+		end = len(rep.Duplicates)
+	}
+	for i := m.dupScroll; i < end; i++ {
+		d := rep.Duplicates[i]
+		prefix := "  "
+		if i == m.dupCursor {
+			prefix = cursorStyle.Render(">") + " "
+		}
+		wasted := sizeColorStyle(d.Wasted).Render(fmt.Sprintf("%8s", utils.HumanizeBytesCompact(d.Wasted)))
+		line := fmt.Sprintf("%s%s %4d× %8s  %s", prefix, wasted, d.Count, utils.HumanizeBytesCompact(d.Size), d.Name+"@"+d.Version)
+		b.WriteString(line + "\n")
+		if i == m.dupOpen {
+			for _, c := range d.Copies {
+				b.WriteString(lockStyle.Render("      "+m.displayPath(c.Path)) + "\n")
+			}
+		}
+	}
+	if m.showHelp {
+		b.WriteString("\n" + m.helpText())
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"node-module-man/internal/scanner"
+)
+
+// errorsPerKind caps the paths listed under each kind in the error panel.
+const errorsPerKind = 5
+
+// errorHints suggest a way out for the kinds that usually have one.
+var errorHints = map[scanner.ErrorKind]string{
+	scanner.ErrPermission: "run as a user that can read these paths, or skip them with --exclude or a .nmmignore file",
+	scanner.ErrLoop:       "symlink cycle; rescan without -L/--follow-symlinks",
+}
+
+// errorsPanel lists the scan errors grouped by kind below the list when the
+// panel is toggled on.
+func (m *model) errorsPanel() string {
+	if !m.showErrors {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("\nScan errors: %d (press e to close)\n", len(m.scanErrs)))
+	if len(m.scanErrs) == 0 {
+		b.WriteString("  none\n")
+		return b.String()
+	}
+	groups := scanner.GroupErrors(m.scanErrs)
+	for _, kind := range scanner.ErrorKinds {
+		errs := groups[kind]
+		if len(errs) == 0 {
+			continue
+		}
+		b.WriteString(errorStyle.Render(fmt.Sprintf("  %s (%d)", kind, len(errs))) + "\n")
+		if hint, ok := errorHints[kind]; ok {
+			b.WriteString(pendingStyle.Render("    hint: "+hint) + "\n")
+		}
+		for i, e := range errs {
+			if i == errorsPerKind {
+				b.WriteString(fmt.Sprintf("    … %d more\n", len(errs)-i))
+				break
+			}
+			b.WriteString(fmt.Sprintf("    %s %s: %v\n", e.Op, m.displayPath(e.Path), e.Err))
+		}
+	}
+	return b.String()
+}
+
+// errorsSummary is a one-line account of the errors of a detail view: the
+// count per kind and the first failure.
+func (m *model) errorsSummary(errs []*scanner.ScanError) string {
+	groups := scanner.GroupErrors(errs)
+	var kinds []string
+	for _, kind := range scanner.ErrorKinds {
+		if n := len(groups[kind]); n > 0 {
+			kinds = append(kinds, fmt.Sprintf("%d %s", n, kind))
+		}
+	}
+	first := errs[0]
+	return errorStyle.Render(fmt.Sprintf("Errors: %s; first: %s %s: %v", strings.Join(kinds, ", "), first.Op, m.displayPath(first.Path), first.Err))
+}
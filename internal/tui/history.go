@@ -0,0 +1,196 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"node-module-man/internal/compressor"
+	"node-module-man/internal/journal"
+	"node-module-man/pkg/utils"
+)
+
+type historyDoneMsg struct {
+	entries []journal.Entry
+	err     error
+}
+
+type restoreDoneMsg struct {
+	sum        compressor.RestoreSummary
+	journalErr error
+}
+
+// startHistory loads the operations journal for the history view.
+func (m model) startHistory() (tea.Model, tea.Cmd) {
+	m.st = statusHistory
+	m.histLoading = true
+	m.histEntries, m.histErr = nil, nil
+	m.histCursor, m.histScroll = 0, 0
+	m.histConfirm, m.histBusy, m.histMsg = false, false, ""
+	return m, loadHistory
+}
+
+func loadHistory() tea.Msg {
+	entries, err := journal.Journal{}.Entries()
+	return historyDoneMsg{entries: entries, err: err}
+}
+
+func (m model) handleHistoryDone(msg historyDoneMsg) (tea.Model, tea.Cmd) {
+	if m.st != statusHistory || !m.histLoading {
+		return m, nil
+	}
+	m.histLoading = false
+	m.histErr = msg.err
+	// newest first
+	m.histEntries = m.histEntries[:0]
+	for i := len(msg.entries) - 1; i >= 0; i-- {
+		m.histEntries = append(m.histEntries, msg.entries[i])
+	}
+	if m.histCursor >= len(m.histEntries) {
+		m.histCursor = 0
+	}
+	return m, nil
+}
+
+// startRestore extracts the archive of the compress entry under the cursor
+// back to where it came from, replacing an existing directory only with
+// overwrite.
+func (m model) startRestore(overwrite bool) (tea.Model, tea.Cmd) {
+	e := m.histEntries[m.histCursor]
+	m.histConfirm = false
+	m.histBusy = true
+	m.histMsg = ""
+	return m, func() tea.Msg {
+		sum := compressor.DecompressTargets(context.Background(), []compressor.ArchiveTarget{e.ArchiveTarget()}, compressor.DecompressOptions{NoOverwrite: !overwrite}, nil)
+		return restoreDoneMsg{sum: sum, journalErr: journal.Journal{}.Record(journal.FromDecompress(sum)...)}
+	}
+}
+
+func (m model) handleRestoreDone(msg restoreDoneMsg) (tea.Model, tea.Cmd) {
+	m.histBusy = false
+	for _, r := range msg.sum.Restored {
+		m.histMsg = fmt.Sprintf("Restored %s (%s); rescan to list it.", r.Path, utils.HumanizeBytes(r.Size))
+	}
+	for _, f := range msg.sum.Failures {
+		m.histMsg = fmt.Sprintf("Restore failed: %v", f.Err)
+	}
+	if msg.journalErr != nil {
+		m.histMsg += fmt.Sprintf(" Failed to update the operations journal: %v", msg.journalErr)
+	}
+	if m.st != statusHistory {
+		return m, nil
+	}
+	m.histLoading = true
+	return m, loadHistory
+}
+
+func (m model) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	n := len(m.histEntries)
+	if m.histConfirm {
+		switch msg.String() {
+		case "y", "Y":
+			return m.startRestore(false)
+		case "o":
+			return m.startRestore(true)
+		default:
+			m.histConfirm = false
+			return m, nil
+		}
+	}
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q", "h", "left", "backspace":
+		m.st = statusReady
+		m.histLoading = false
+		return m, nil
+	case "?":
+		m.showHelp = !m.showHelp
+	case "up", "k":
+		if m.histCursor > 0 {
+			m.histCursor--
+		}
+	case "down", "j":
+		if m.histCursor < n-1 {
+			m.histCursor++
+		}
+	case "home", "g":
+		m.histCursor = 0
+	case "end", "G":
+		if n > 0 {
+			m.histCursor = n - 1
+		}
+	case "r":
+		if n == 0 || m.histBusy || m.histLoading {
+			break
+		}
+		if m.histEntries[m.histCursor].Op != journal.OpCompress {
+			m.histMsg = "Only compress entries have an archive to restore."
+			break
+		}
+		m.histConfirm = true
+		m.histMsg = ""
+	}
+	h := m.inspectHeight()
+	if m.histCursor >= m.histScroll+h {
+		m.histScroll = m.histCursor - h + 1
+	}
+	if m.histCursor < m.histScroll {
+		m.histScroll = m.histCursor
+	}
+	return m, nil
+}
+
+func (m *model) historyView() string {
+	var b strings.Builder
+	if m.histLoading && m.histEntries == nil {
+		b.WriteString(fmt.Sprintf("History: reading the operations journal... %s\nPress esc to go back.\n", m.sp.View()))
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("History: %d operations  | Keys: ↑↓ move, r restore archive, esc/h back, ? help\n", len(m.histEntries)))
+	if m.histErr != nil {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Failed to read the operations journal: %v", m.histErr)) + "\n")
+	}
+	switch {
+	case m.histConfirm:
+		e := m.histEntries[m.histCursor]
+		b.WriteString(fmt.Sprintf("Restore %s from %s? y restore (refused if it exists), o replace an existing one, any other key cancels\n", e.Path, e.Archive))
+	case m.histBusy:
+		b.WriteString(fmt.Sprintf("Restoring... %s\n", m.sp.View()))
+	case m.histMsg != "":
+		b.WriteString(m.histMsg + "\n")
+	}
+	b.WriteString("\n")
+	if len(m.histEntries) == 0 {
+		b.WriteString("Nothing deleted, compressed or restored yet.\n")
+		return b.String()
+	}
+	end := m.histScroll + m.inspectHeight()
+	if end > len(m.histEntries) {
+		end = len(m.histEntries)
+	}
+	for i := m.histScroll; i < end; i++ {
+		e := m.histEntries[i]
+		prefix := "  "
+		if i == m.histCursor {
+			prefix = cursorStyle.Render(">") + " "
+		}
+		size := sizeColorStyle(e.Size).Render(fmt.Sprintf("%8s", utils.HumanizeBytesCompact(e.Size)))
+		line := fmt.Sprintf("%s%5s ago  %-10s %s  %s", prefix, utils.HumanizeAge(time.Since(e.Time)), e.Op, size, e.Path)
+		if e.Archive != "" {
+			removed := ""
+			if e.Removed {
+				removed = ", source removed"
+			}
+			line += fmt.Sprintf("  → %s (%s%s)", e.Archive, utils.HumanizeBytes(e.ArchiveSize), removed)
+		}
+		b.WriteString(line + "\n")
+	}
+	if m.showHelp {
+		b.WriteString("\n" + m.helpText())
+	}
+	return b.String()
+}
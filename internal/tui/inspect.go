@@ -0,0 +1,186 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"node-module-man/internal/scanner"
+	"node-module-man/pkg/utils"
+)
+
+// inspectLevel is one node_modules directory on the drill-down stack.
+type inspectLevel struct {
+	dir    string
+	pkgs   []scanner.Package
+	errs   []*scanner.ScanError
+	cursor int
+	scroll int
+}
+
+type inspectDoneMsg struct {
+	dir  string
+	pkgs []scanner.Package
+	errs []*scanner.ScanError
+}
+
+func (m *model) inspectCmd(dir string) tea.Cmd {
+	opts := m.opts
+	return func() tea.Msg {
+		pkgs, errs := scanner.Inspect(context.Background(), dir, opts)
+		return inspectDoneMsg{dir: dir, pkgs: pkgs, errs: errs}
+	}
+}
+
+// startInspect opens the detail view for the item under the cursor.
+func (m model) startInspect() (tea.Model, tea.Cmd) {
+	view := m.viewIndexes()
+	if m.cursor >= len(view) {
+		return m, nil
+	}
+	dir := m.items[view[m.cursor]].path
+	m.st = statusInspect
+	m.inspStack = nil
+	m.inspLoading = dir
+	return m, m.inspectCmd(dir)
+}
+
+func (m model) handleInspectDone(msg inspectDoneMsg) (tea.Model, tea.Cmd) {
+	if m.st != statusInspect || msg.dir != m.inspLoading {
+		return m, nil // user backed out before it finished
+	}
+	m.inspLoading = ""
+	m.inspStack = append(m.inspStack, inspectLevel{dir: msg.dir, pkgs: msg.pkgs, errs: msg.errs})
+	return m, nil
+}
+
+func (m model) updateInspect(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+	if msg.String() == "?" {
+		m.showHelp = !m.showHelp
+		return m, nil
+	}
+	if m.inspLoading != "" {
+		switch msg.String() {
+		case "esc", "q", "h", "left", "backspace":
+			m.inspLoading = ""
+			if len(m.inspStack) == 0 {
+				m.st = statusReady
+			}
+		}
+		return m, nil
+	}
+	if len(m.inspStack) == 0 {
+		m.st = statusReady
+		return m, nil
+	}
+	lv := &m.inspStack[len(m.inspStack)-1]
+	switch msg.String() {
+	case "up", "k":
+		if lv.cursor > 0 {
+			lv.cursor--
+		}
+	case "down", "j":
+		if lv.cursor < len(lv.pkgs)-1 {
+			lv.cursor++
+		}
+	case "home", "g":
+		lv.cursor = 0
+	case "end", "G":
+		if len(lv.pkgs) > 0 {
+			lv.cursor = len(lv.pkgs) - 1
+		}
+	case "enter", "l", "right":
+		if lv.cursor < len(lv.pkgs) && lv.pkgs[lv.cursor].Nested {
+			dir := filepath.Join(lv.pkgs[lv.cursor].Path, "node_modules")
+			m.inspLoading = dir
+			return m, m.inspectCmd(dir)
+		}
+	case "esc", "q", "h", "left", "backspace":
+		m.inspStack = m.inspStack[:len(m.inspStack)-1]
+		if len(m.inspStack) == 0 {
+			m.st = statusReady
+		}
+		return m, nil
+	}
+	h := m.inspectHeight()
+	if lv.cursor >= lv.scroll+h {
+		lv.scroll = lv.cursor - h + 1
+	}
+	if lv.cursor < lv.scroll {
+		lv.scroll = lv.cursor
+	}
+	return m, nil
+}
+
+// inspectHeight is the number of package rows that fit under the header.
+func (m *model) inspectHeight() int {
+	h := m.termH - 5
+	if h < 3 {
+		h = 3
+	}
+	return h
+}
+
+func (m *model) inspectView() string {
+	var b strings.Builder
+	crumbs := make([]string, 0, len(m.inspStack)+1)
+	for _, lv := range m.inspStack {
+		crumbs = append(crumbs, m.displayPath(lv.dir))
+	}
+	if m.inspLoading != "" {
+		crumbs = append(crumbs, m.displayPath(m.inspLoading))
+	}
+	b.WriteString("Inspect: " + strings.Join(crumbs, " › ") + "\n")
+	if m.inspLoading != "" {
+		b.WriteString(fmt.Sprintf("Measuring packages... %s\nPress esc to go back.\n", m.sp.View()))
+		return b.String()
+	}
+	lv := m.inspStack[len(m.inspStack)-1]
+	var total int64
+	for _, p := range lv.pkgs {
+		total += p.Size
+	}
+	b.WriteString(fmt.Sprintf("Packages: %d  Apparent: %s  | Keys: ↑↓ move, enter/l open nested node_modules, esc/h back, ? help\n", len(lv.pkgs), utils.HumanizeBytes(total)))
+	if len(lv.errs) > 0 {
+		b.WriteString(m.errorsSummary(lv.errs) + "\n")
+	}
+	b.WriteString("\n")
+	if len(lv.pkgs) == 0 {
+		b.WriteString("No packages found.\n")
+		return b.String()
+	}
+	end := lv.scroll + m.inspectHeight()
+	if end > len(lv.pkgs) {
+		end = len(lv.pkgs)
+	}
+	for i := lv.scroll; i < end; i++ {
+		p := lv.pkgs[i]
+		prefix := "  "
+		if i == lv.cursor {
+			prefix = cursorStyle.Render(">") + " "
+		}
+		version := p.Version
+		if version == "" {
+			version = "-"
+		}
+		name := p.Name
+		if p.Nested {
+			name += kindStyle.Render(" ▸ node_modules")
+		}
+		if p.Symlink {
+			name += lockStyle.Render(" → symlink")
+		}
+		sizeStr := sizeColorStyle(p.Size).Render(fmt.Sprintf("%8s", utils.HumanizeBytesCompact(p.Size)))
+		b.WriteString(prefix + sizeStr + " " + lockStyle.Render(padRight(truncate(version, 12), 12)) + " " + name + "\n")
+	}
+	if m.showHelp {
+		b.WriteString("\n" + m.helpText())
+	}
+	return b.String()
+}
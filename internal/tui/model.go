@@ -14,6 +14,7 @@ import (
 
 	"node-module-man/internal/compressor"
 	"node-module-man/internal/deleter"
+	"node-module-man/internal/journal"
 	"node-module-man/internal/scanner"
 	"node-module-man/pkg/utils"
 )
@@ -29,6 +30,10 @@ const (
 	statusZipConfirm
 	statusZipping
 	statusZipDone
+	statusInspect
+	statusDupes
+	statusCaches
+	statusHistory
 )
 
 type model struct {
@@ -38,44 +43,94 @@ type model struct {
 	startedAt time.Time
 
 	st        status
-	results   []scanner.ResultItem
-	totalSize int64
-	err       error
+	results   []scanner.ResultItem // sized results only
+	totalSize int64 // apparent
+	totalReclaimable int64
+	scanErrs  []*scanner.ScanError
 
 	// list view (custom rendering, not using bubbles/list)
 	items        []item
+	itemIdx      map[string]int // path -> index in items
+	unsorted     bool           // scan events arrived since the last sort
 	cursor       int
 	scrollOffset int
-	sortBy       string // "size" or "path"
+	sortBy       string // "size", "path", "name", "age" or "files"
 	sortReverse  bool
 	selectedSize int64
 	zipSelectedSize int64
 	filterText   string
 	filtering    bool
+	kindFilter   string // "" shows every target kind
+	driftFilter  scanner.DriftStatus // "" shows every lockfile drift status
 
 	// confirm/delete state
 	delCh        chan tea.Msg
 	delTotal     int
 	delCompleted int
 	delLastPath  string
+	delStarted   time.Time
+	delFilesDone int64
+	delFilesTotal int64
 	delFreed     int64
+	delTrashed   int64
+	delQuarantined int64
 	delFailures  []deleter.Failure
 
 	// deletion control
 	delCancel func()
 	dryRun    bool
+	force     bool // delete protected (git-tracked) targets too
+	removal   int  // index into removals; T cycles
 
 	// compression state
 	zipCh        chan tea.Msg
 	zipTotal     int
 	zipCompleted int
 	zipLastPath  string
+	zipStarted   time.Time
+	zipFilesDone int64
+	zipFilesTotal int64
 	zipLastDest  string
 	zipWritten   int64
     zipFailures  []compressor.Failure
     zipCancel    func()
     zipDeleteAfter bool
 
+	// package drill-down (see inspect.go)
+	inspStack   []inspectLevel
+	inspLoading string // directory being measured; "" when idle
+
+	// duplicate-package report (see dupes.go)
+	dupReport  scanner.DuplicateReport
+	dupErrs    []*scanner.ScanError
+	dupLoading bool
+	dupCursor  int
+	dupScroll  int
+	dupOpen    int // index whose copies are listed; -1 for none
+
+	// package manager caches (see caches.go)
+	cacheReport  scanner.CacheReport
+	cacheErrs    []*scanner.ScanError
+	cacheLoading bool
+	cacheCursor  int
+	cacheScroll  int
+	cachePlan    *scanner.PrunePlan // prune awaiting confirmation
+	cachePlanErr error
+	cachePlanFor string // what cachePlan prunes, for display
+	cacheBusy    string // "planning ..." or "pruning ..."; "" when idle
+	cacheMsg     string // outcome of the last prune
+
+	// operations journal (see history.go)
+	histEntries []journal.Entry // newest first
+	histErr     error
+	histLoading bool
+	histCursor  int
+	histScroll  int
+	histConfirm bool   // restore of the cursor's archive awaiting confirmation
+	histBusy    bool   // a restore is running
+	histMsg     string // outcome of the last restore
+	journalErr  error // the last delete or compress could not be journaled
+
 	// scanning stream
 	scanCh     chan tea.Msg
 	scanCancel func()
@@ -87,12 +142,14 @@ type model struct {
 
     // help panel
     showHelp bool
+	// scan error panel, grouped by kind
+	showErrors bool
 
     // navigation helpers
     lastG bool
 }
 
-func newModel(path string, opts scanner.Options, dryRun bool) model {
+func newModel(path string, opts scanner.Options, dryRun, force bool, removal string) model {
 	sp := spinner.New()
 	sp.Spinner = spinner.Dot
     m := model{
@@ -102,7 +159,10 @@ func newModel(path string, opts scanner.Options, dryRun bool) model {
         startedAt:   time.Now(),
         st:          statusScanning,
         dryRun:      dryRun,
+        force:       force,
+        removal:     removalIndex(removal),
         items:       []item{},
+        itemIdx:     map[string]int{},
         cursor:      0,
         sortBy:      "size",
         sortReverse: true,
@@ -112,27 +172,23 @@ func newModel(path string, opts scanner.Options, dryRun bool) model {
 	ch := make(chan tea.Msg)
 	m.scanCh = ch
 	m.scanning = true
+	ctx, cancel := context.WithCancel(context.Background())
+	m.scanCancel = cancel
 	go func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		m.scanCancel = cancel
-		out, errCh := scanner.ScanNodeModulesStream(ctx, path, opts)
-		for r := range out {
-			ch <- scanItemMsg{item: r}
-		}
-		// final error (may be nil)
-		var err error
-		if e, ok := <-errCh; ok {
-			err = e
+		for ev := range scanner.ScanNodeModulesStream(ctx, path, opts) {
+			ch <- scanEventMsg{ev: ev}
 		}
-		ch <- scanCompleteMsg{err: err}
+		ch <- scanCompleteMsg{}
 		close(ch)
 	}()
 	return m
 }
 
 // public entry
-func Run(path string, opts scanner.Options, dryRun bool) error {
-	m := newModel(path, opts, dryRun)
+// removal is "delete", "trash" or "quarantine": how d removes [x] items
+// until T switches it.
+func Run(path string, opts scanner.Options, dryRun, force bool, removal string) error {
+	m := newModel(path, opts, dryRun, force, removal)
 	p := tea.NewProgram(m)
 	_, err := p.Run()
 	return err
@@ -142,14 +198,14 @@ func Run(path string, opts scanner.Options, dryRun bool) error {
 type scanDoneMsg struct {
 	results   []scanner.ResultItem
 	totalSize int64
-	err       error
+	errs      []*scanner.ScanError
 }
 
 func scanCmd(path string, opts scanner.Options) tea.Cmd {
 	return func() tea.Msg {
 		// Run sync scan in background
-		res, total, err := scanner.ScanNodeModules(context.Background(), path, opts)
-		return scanDoneMsg{results: res, totalSize: total, err: err}
+		res, total, errs := scanner.ScanNodeModules(context.Background(), path, opts)
+		return scanDoneMsg{results: res, totalSize: total, errs: errs}
 	}
 }
 
@@ -164,6 +220,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
         if msg.String() != "g" {
             m.lastG = false
         }
+        if m.unsorted {
+            m.resort() // keys act on the list as displayed after sorting
+        }
+        if m.st == statusInspect {
+            return m.updateInspect(msg)
+        }
+        if m.st == statusDupes {
+            return m.updateDupes(msg)
+        }
+        if m.st == statusCaches {
+            return m.updateCaches(msg)
+        }
+        if m.st == statusHistory {
+            return m.updateHistory(msg)
+        }
         // Filtering text input handling
         if m.filtering {
             s := msg.String()
@@ -225,12 +296,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
             m.showHelp = !m.showHelp
             return m, nil
         case "/":
-            if m.st == statusReady {
+            if m.browsing() {
                 m.filtering = true
                 // keep existing filterText (acts like search refine)
                 return m, nil
             }
-        case "enter", "d":
+        case "enter", "l":
+            if m.st == statusReady {
+                return m.startInspect()
+            }
+        case "d":
+            // deleting and compressing wait for the scan to finish
             if m.st == statusReady {
                 if m.selectedCount() > 0 {
                     m.st = statusConfirm
@@ -259,7 +335,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                 return m, nil
             }
         case "up", "k":
-            if m.st == statusReady {
+            if m.browsing() {
                 if m.cursor > 0 {
                     m.cursor--
                 }
@@ -267,7 +343,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                 return m, nil
             }
         case "down", "j":
-            if m.st == statusReady {
+            if m.browsing() {
                 view := m.viewIndexes()
                 if m.cursor < len(view)-1 {
                     m.cursor++
@@ -276,7 +352,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                 return m, nil
             }
         case "ctrl+f":
-            if m.st == statusReady {
+            if m.browsing() {
                 step := m.visibleHeight()
                 view := m.viewIndexes()
                 m.cursor += step
@@ -288,7 +364,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                 return m, nil
             }
         case "ctrl+b":
-            if m.st == statusReady {
+            if m.browsing() {
                 step := m.visibleHeight()
                 m.cursor -= step
                 if m.cursor < 0 { m.cursor = 0 }
@@ -296,13 +372,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                 return m, nil
             }
         case "home":
-            if m.st == statusReady {
+            if m.browsing() {
                 m.cursor = 0
                 m.adjustScroll()
                 return m, nil
             }
         case "end":
-            if m.st == statusReady {
+            if m.browsing() {
                 view := m.viewIndexes()
                 if len(view) > 0 {
                     m.cursor = len(view) - 1
@@ -313,7 +389,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                 return m, nil
             }
         case "g":
-            if m.st == statusReady {
+            if m.browsing() {
                 if m.lastG {
                     // gg -> top
                     m.cursor = 0
@@ -325,7 +401,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                 return m, nil
             }
         case "G":
-            if m.st == statusReady {
+            if m.browsing() {
                 view := m.viewIndexes()
                 if len(view) > 0 {
                     m.cursor = len(view) - 1
@@ -336,49 +412,90 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                 return m, nil
             }
         case " ":
-            if m.st == statusReady {
+            if m.browsing() {
                 m.toggleSelected()
                 return m, nil
             }
         case "x":
-            if m.st == statusReady {
+            if m.browsing() {
                 m.toggleSelected()
                 return m, nil
             }
         case "z":
-            if m.st == statusReady {
+            if m.browsing() {
                 m.toggleCompressSelected()
                 return m, nil
             }
 		case "A", "ctrl+a":
-			if m.st == statusReady {
+			if m.browsing() {
 				m.selectAllVisible()
 				return m, nil
 			}
 		case "R", "ctrl+r":
-			if m.st == statusReady {
+			if m.browsing() {
 				m.reverseSelectionVisible()
 				return m, nil
 			}
 		case "s":
-			if m.st == statusReady {
+			if m.browsing() {
 				m.toggleSortField()
 				m.applySort()
 				return m, nil
 			}
 		case "r":
-			if m.st == statusReady {
+			if m.browsing() {
 				m.sortReverse = !m.sortReverse
 				m.applySort()
 				return m, nil
 			}
 		case "Z":
-			if m.st == statusReady {
+			if m.browsing() {
 				m.selectAllZipVisible()
 				return m, nil
 			}
-		case "X":
+		case "t":
+			if m.browsing() {
+				m.cycleKindFilter()
+				return m, nil
+			}
+		case "L":
+			if m.browsing() {
+				m.cycleDriftFilter()
+				return m, nil
+			}
+		case "O":
+			if m.browsing() {
+				m.selectOrphans()
+				return m, nil
+			}
+		case "e":
+			if m.browsing() {
+				m.showErrors = !m.showErrors
+				return m, nil
+			}
+		case "m":
+			if m.browsing() {
+				return m, m.startRemeasure()
+			}
+		case "D":
+			if m.st == statusReady {
+				return m.startDupes()
+			}
+		case "C":
 			if m.st == statusReady {
+				return m.startCaches()
+			}
+		case "H":
+			if m.st == statusReady {
+				return m.startHistory()
+			}
+		case "T":
+			if m.browsing() || m.st == statusConfirm {
+				m.removal = (m.removal + 1) % len(removals)
+				return m, nil
+			}
+		case "X":
+			if m.browsing() {
 				m.selectAllVisible()
 				return m, nil
 			}
@@ -390,6 +507,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.sp, cmd = m.sp.Update(msg)
+		if m.unsorted {
+			// scan events are sorted in at the spinner's pace, not one by one
+			m.resort()
+		}
 		// keep polling scan/delete channels too
 		if m.st == statusScanning {
 			return m, tea.Batch(cmd, m.waitScanMsg())
@@ -401,17 +522,35 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Batch(cmd, m.waitZipMsg())
 		}
 		return m, cmd
-	case scanItemMsg:
-		m.appendResult(msg.item)
+	case remeasureDoneMsg:
+		m.applyRemeasure(msg.r)
+		return m, nil
+	case inspectDoneMsg:
+		return m.handleInspectDone(msg)
+	case dupesDoneMsg:
+		return m.handleDupesDone(msg)
+	case cachesDoneMsg:
+		return m.handleCachesDone(msg)
+	case historyDoneMsg:
+		return m.handleHistoryDone(msg)
+	case restoreDoneMsg:
+		return m.handleRestoreDone(msg)
+	case prunePlanMsg:
+		return m.handlePrunePlan(msg)
+	case pruneDoneMsg:
+		return m.handlePruneDone(msg)
+	case scanEventMsg:
+		m.applyScanEvent(msg.ev)
 		return m, m.waitScanMsg()
 case scanCompleteMsg:
-		m.err = msg.err
+		m.resort()
 		m.scanning = false
 		m.st = statusReady
 		return m, nil
 case delProgressMsg:
 		m.delCompleted = msg.completed
 		m.delLastPath = msg.path
+		m.delFilesDone, m.delFilesTotal = msg.filesDone, msg.filesTotal
 		if msg.err == nil {
 			// Freed are already tracked in summary later; optimistic update here for UX
 		}
@@ -419,16 +558,19 @@ case delProgressMsg:
 	case delDoneMsg:
 			m.delFailures = msg.summary.Failures
 			m.delFreed = msg.summary.Freed
+			m.delTrashed = msg.summary.Trashed
+			m.delQuarantined = msg.summary.Quarantined
+			m.journalErr = msg.journalErr
 			// remove successes from list and results
 			succ := msg.summary.Successes
 			m.removeDeleted(succ)
 			m.selectedSize = 0
-			m.totalSize -= m.delFreed
 			m.st = statusDone
 			return m, nil
 	case zipProgressMsg:
 			m.zipCompleted = msg.completed
 			m.zipLastPath = msg.path
+			m.zipFilesDone, m.zipFilesTotal = msg.filesDone, msg.filesTotal
 			m.zipLastDest = msg.dest
 			if msg.err == nil {
 				m.zipWritten = msg.written
@@ -437,18 +579,18 @@ case delProgressMsg:
     case zipDoneMsg:
             m.zipFailures = msg.summary.Failures
             m.zipWritten = msg.summary.Written
+            m.journalErr = msg.journalErr
             // If we deleted sources after compress, remove them from list and adjust totals
             if m.zipDeleteAfter {
-                // build targets from successes to reuse removeDeleted
+                // build targets from successes to reuse removeDeleted; sources
+                // that failed delete-after (protected ones) stay listed
+                kept := make(map[string]bool, len(msg.summary.Failures))
+                for _, f := range msg.summary.Failures { kept[f.Path] = true }
                 succ := make([]deleter.Target, 0, len(msg.summary.Successes))
-                freed := int64(0)
-                // quick lookup set
-                rm := make(map[string]struct{}, len(msg.summary.Successes))
-                for _, s := range msg.summary.Successes { rm[s.Path] = struct{}{} }
-                for _, it := range m.items { if _, ok := rm[it.path]; ok { freed += it.size } }
-                for _, s := range msg.summary.Successes { succ = append(succ, deleter.Target{Path: s.Path}) }
+                for _, s := range msg.summary.Successes {
+                    if !kept[s.Path] { succ = append(succ, deleter.Target{Path: s.Path}) }
+                }
                 m.removeDeleted(succ)
-                m.totalSize -= freed
             } else {
                 // Clear zip selections on success (but keep items)
                 for i := range m.items { m.items[i].selZip = false }
@@ -470,42 +612,80 @@ case delProgressMsg:
 func (m model) View() string {
     switch m.st {
     case statusScanning:
-        base := m.headerText() + m.renderList()
+        base := m.headerText() + m.renderList() + m.errorsPanel()
         if m.showHelp {
             base += "\n" + m.helpText()
         }
         return base
     case statusReady:
-        base := m.headerText() + m.renderList()
+        base := m.headerText() + m.renderList() + m.errorsPanel()
         if m.showHelp {
             base += "\n" + m.helpText()
         }
         return base
+	case statusInspect:
+		return m.inspectView()
+	case statusDupes:
+		return m.dupesView()
+	case statusCaches:
+		return m.cachesView()
+	case statusHistory:
+		return m.historyView()
 	case statusConfirm:
 		cnt := m.selectedCount()
 		size := utils.HumanizeBytes(m.selectedSize)
-		return fmt.Sprintf("Confirm delete %d node_modules, freeing ~%s? (y/N)\nPress y to confirm, n/esc to cancel.\n", cnt, size)
+		s := fmt.Sprintf("Confirm delete %d node_modules, freeing ~%s? (y/N)\n", cnt, size)
+		switch removals[m.removal].name {
+		case "trash":
+			s = fmt.Sprintf("Confirm moving %d node_modules (~%s) to the trash? (y/N)\n", cnt, size)
+		case "quarantine":
+			s = fmt.Sprintf("Confirm quarantining %d node_modules (~%s)? They stay restorable until `node-module-man quarantine purge`. (y/N)\n", cnt, size)
+		}
+		if n := m.selectedProtectedCount(); n > 0 {
+			if m.force {
+				s += errorStyle.Render(fmt.Sprintf("%d of them are tracked in git and WILL be deleted (--force).", n)) + "\n"
+			} else {
+				s += errorStyle.Render(fmt.Sprintf("%d of them are tracked in git and will be skipped; rerun with --force to delete them.", n)) + "\n"
+			}
+		}
+		s += m.restoreSummary()
+		return s + "Press y to confirm, n/esc to cancel, T to switch between delete, trash and quarantine.\n"
     case statusZipConfirm:
         cnt := m.selectedZipCount()
         size := utils.HumanizeBytes(m.zipSelectedSize)
         return fmt.Sprintf("Confirm compress %d node_modules to zip (~%s)? (y/N)\nOriginals will be deleted after successful compression (default).\nPress y to confirm, n/esc to cancel.\n", cnt, size)
     case statusDeleting:
         mode := ""
+        switch removals[m.removal].name {
+        case "trash":
+            mode = " to trash"
+        case "quarantine":
+            mode = " into quarantine"
+        }
         if m.dryRun {
-            mode = " [dry-run]"
+            mode += " [dry-run]"
         }
-        return fmt.Sprintf("Deleting%s... %s\nProgress: %d/%d\nLast: %s\nPress q/ctrl+c/ctrl+d to cancel.\n", mode, m.sp.View(), m.delCompleted, m.delTotal, m.delLastPath)
+        return fmt.Sprintf("Deleting%s... %s\nProgress: %s\nLast: %s\nPress q/ctrl+c/ctrl+d to cancel.\n", mode, m.sp.View(), progressLine(m.delCompleted, m.delTotal, m.delFilesDone, m.delFilesTotal, m.delStarted), m.delLastPath)
     case statusZipping:
-        return fmt.Sprintf("Compressing... %s\nProgress: %d/%d\nLast: %s\nDest: %s\nWritten: %s\nPress q/ctrl+c/ctrl+d to cancel.\n", m.sp.View(), m.zipCompleted, m.zipTotal, m.zipLastPath, m.zipLastDest, utils.HumanizeBytes(m.zipWritten))
+        return fmt.Sprintf("Compressing... %s\nProgress: %s\nLast: %s\nDest: %s\nWritten: %s\nPress q/ctrl+c/ctrl+d to cancel.\n", m.sp.View(), progressLine(m.zipCompleted, m.zipTotal, m.zipFilesDone, m.zipFilesTotal, m.zipStarted), m.zipLastPath, m.zipLastDest, utils.HumanizeBytes(m.zipWritten))
 	case statusDone:
 		mode := ""
 		if m.dryRun {
 			mode = " (dry-run; no files removed)"
 		}
 		s := fmt.Sprintf("Delete complete%s. Freed %s. Failures: %d\n", mode, utils.HumanizeBytes(m.delFreed), len(m.delFailures))
+		if m.delTrashed > 0 {
+			s = fmt.Sprintf("Delete complete%s. Moved %s to the trash. Failures: %d\n", mode, utils.HumanizeBytes(m.delTrashed), len(m.delFailures))
+		}
+		if m.delQuarantined > 0 {
+			s = fmt.Sprintf("Delete complete%s. Quarantined %s; `node-module-man quarantine list` shows how to restore it. Failures: %d\n", mode, utils.HumanizeBytes(m.delQuarantined), len(m.delFailures))
+		}
 		for _, f := range m.delFailures {
 			s += fmt.Sprintf(" - %s: %v\n", f.Path, f.Err)
 		}
+		if m.journalErr != nil {
+			s += errorStyle.Render(fmt.Sprintf("Failed to update the operations journal: %v", m.journalErr)) + "\n"
+		}
 		s += "Press q to quit or any key to return.\n"
 		return s
 	case statusZipDone:
@@ -513,6 +693,9 @@ func (m model) View() string {
 		for _, f := range m.zipFailures {
 			s += fmt.Sprintf(" - %s: %v\n", f.Path, f.Err)
 		}
+		if m.journalErr != nil {
+			s += errorStyle.Render(fmt.Sprintf("Failed to update the operations journal: %v", m.journalErr)) + "\n"
+		}
 		s += "Press q to quit or any key to return.\n"
 		return s
 	default:
@@ -524,16 +707,30 @@ func (m model) View() string {
 type item struct {
     path string
     disp string
-    size int64
+    size int64 // reclaimable bytes; drives selection totals and deletion
+    apparent int64
     err  error
+    pending bool // discovered, size still being calculated
+    partial bool // size is a lower bound: a scan budget ran out
+    files int64
+    dirs  int64
+    symlinks int64
     sel  bool
     selZip bool
+    kind string
+    project scanner.ProjectInfo
+    activity time.Time
+    health scanner.Health
+    drift scanner.DriftStatus // "" when not compared
+    commit time.Time // last commit of the owning git repository
+    protected bool // tracked in git
+    restore *scanner.Restorability // nil until sized, and for kinds other than node_modules
 }
 
 // Custom list rendering - no bubbles/list component
 func (m *model) renderList() string {
     if len(m.items) == 0 {
-        return "No node_modules found.\n"
+        return "No targets found.\n"
     }
 
     var b strings.Builder
@@ -566,18 +763,46 @@ func (m *model) renderList() string {
 		}
 
 		sizeStr := sizeColorStyle(it.size).Render(utils.HumanizeBytesCompact(it.size))
+		if it.pending {
+			sizeStr = pendingStyle.Render("calculating…")
+		} else if it.partial {
+			sizeStr = sizeColorStyle(it.size).Render("≥ "+utils.HumanizeBytesCompact(it.size)) + pendingStyle.Render(" partial")
+		} else if it.err != nil {
+			sizeStr = errorStyle.Render("error")
+		}
 
 		var pathStr string
+		if it.kind != "" && it.kind != scanner.KindNodeModules {
+			pathStr = kindStyle.Render("["+it.kind+"]") + " "
+		}
+		if it.health != "" && it.health != scanner.HealthOK {
+			pathStr += healthStyle.Render("("+string(it.health)+")") + " "
+		}
+		if it.drift == scanner.DriftStale || it.drift == scanner.DriftExtraneous {
+			pathStr += healthStyle.Render("("+string(it.drift)+")") + " "
+		}
+		if it.protected {
+			pathStr += errorStyle.Render("(tracked in git)") + " "
+		}
 		if it.sel {
-			pathStr = pathStyleSelected.Render(it.disp)
+			pathStr += pathStyleSelected.Render(it.disp)
 		} else if it.selZip {
-			pathStr = pathStyleZip.Render(it.disp)
+			pathStr += pathStyleZip.Render(it.disp)
 		} else {
-			pathStr = it.disp
+			pathStr += it.disp
+		}
+
+		projStr := projectStyle.Render(padRight(truncate(it.project.DisplayName(), projectColWidth), projectColWidth))
+		lockStr := lockStyle.Render(padRight(lockLabel(it.project.Lockfile), 4))
+		ageStr := ageStyle.Render(fmt.Sprintf("%4s", ageLabel(it.activity)))
+		commitStr := lockStyle.Render(fmt.Sprintf("%4s", ageLabel(it.commit)))
+		filesStr := ageStyle.Render(fmt.Sprintf("%6s", "-"))
+		if !it.pending && it.err == nil {
+			filesStr = ageStyle.Render(fmt.Sprintf("%6s", utils.HumanizeCount(it.files)))
 		}
 
 		// Build final line
-		line := prefix + mark + " " + sizeStr + " " + pathStr
+		line := prefix + mark + " " + sizeStr + " " + filesStr + " " + ageStr + " " + commitStr + " " + projStr + " " + lockStr + " " + pathStr
 
 		b.WriteString(line + "\n")
 	}
@@ -671,6 +896,24 @@ func (m *model) selectAllVisible() {
     }
 }
 
+// selectOrphans marks the orphaned node_modules in the filtered view [x]:
+// with no package.json next to them there is nothing to reinstall them for.
+// Protected (git-tracked) ones are left alone.
+func (m *model) selectOrphans() {
+	for _, i := range m.viewIndexes() {
+		it := &m.items[i]
+		if it.health != scanner.HealthOrphan || it.sel || it.protected {
+			continue
+		}
+		if it.selZip {
+			it.selZip = false
+			m.zipSelectedSize -= it.size
+		}
+		it.sel = true
+		m.selectedSize += it.size
+	}
+}
+
 // reverseSelectionVisible applies tri-state invert over visible items:
 // z -> [ ] , x -> [ ] , [ ] -> x
 func (m *model) reverseSelectionVisible() {
@@ -697,9 +940,16 @@ func (m *model) reverseSelectionVisible() {
 }
 
 func (m *model) toggleSortField() {
-	if m.sortBy == "size" {
+	switch m.sortBy {
+	case "size":
 		m.sortBy = "path"
-	} else {
+	case "path":
+		m.sortBy = "name"
+	case "name":
+		m.sortBy = "age"
+	case "age":
+		m.sortBy = "files"
+	default:
 		m.sortBy = "size"
 	}
 }
@@ -712,16 +962,49 @@ func (m *model) applySort() {
 			}
 			return m.items[i].disp < m.items[j].disp
 		}
+		if m.sortBy == "name" {
+			a, b := m.items[i].project.DisplayName(), m.items[j].project.DisplayName()
+			if a == b {
+				return m.items[i].disp < m.items[j].disp
+			}
+			if m.sortReverse {
+				return a > b
+			}
+			return a < b
+		}
+		if m.sortBy == "age" {
+			// older activity means a larger age; reverse puts the stalest first
+			a, b := m.items[i].activity, m.items[j].activity
+			if m.sortReverse {
+				return a.Before(b)
+			}
+			return a.After(b)
+		}
+		if m.sortBy == "files" {
+			if m.sortReverse {
+				return m.items[i].files > m.items[j].files
+			}
+			return m.items[i].files < m.items[j].files
+		}
 		if m.sortReverse {
 			return m.items[i].size > m.items[j].size
 		}
 		return m.items[i].size < m.items[j].size
 	})
+	m.reindex()
+}
+
+// reindex rebuilds the path lookup after items moved.
+func (m *model) reindex() {
+	m.itemIdx = make(map[string]int, len(m.items))
+	for i := range m.items {
+		m.itemIdx[m.items[i].path] = i
+	}
 }
 
 // streaming scan wiring
-type scanItemMsg struct{ item scanner.ResultItem }
-type scanCompleteMsg struct{ err error }
+type scanEventMsg struct{ ev scanner.Event }
+type scanCompleteMsg struct{}
 
 func (m *model) waitScanMsg() tea.Cmd {
 	if m.scanCh == nil {
@@ -736,19 +1019,141 @@ func (m *model) waitScanMsg() tea.Cmd {
 	}
 }
 
-func (m *model) appendResult(r scanner.ResultItem) {
-    m.results = append(m.results, r)
-    if r.Err == nil {
-        m.totalSize += r.Size
-    }
-	// Append to items array and sort
-	m.items = append(m.items, item{
-		path: r.Path,
-		disp: m.displayPath(r.Path),
-		size: r.Size,
-		err:  r.Err,
-	})
-    m.applySort()
+// applyScanEvent lists discovered targets right away and fills in their
+// sizes as they arrive. The list is sorted again on the next spinner tick or
+// key press rather than per event.
+func (m *model) applyScanEvent(ev scanner.Event) {
+	switch e := ev.(type) {
+	case scanner.Discovered:
+		m.items = append(m.items, item{
+			path:     e.Path,
+			disp:     m.displayPath(e.Path),
+			kind:     e.Kind,
+			pending:  true,
+			project:  e.Project,
+			activity: e.LastActivity,
+			health:   e.Health,
+		})
+		m.itemIdx[e.Path] = len(m.items) - 1
+	case scanner.Sized:
+		m.results = append(m.results, e.ResultItem)
+		if e.Err == nil {
+			m.totalSize += e.Size
+			m.totalReclaimable += e.Reclaimable
+		}
+		if i := m.itemIndex(e.Path); i >= 0 {
+			m.setItemSize(i, e.ResultItem)
+		}
+	case scanner.Error:
+		m.scanErrs = append(m.scanErrs, e.ScanError)
+		if i := m.itemIndex(e.Path); i >= 0 {
+			m.items[i].pending = false
+			m.items[i].err = e.ScanError
+		}
+	}
+	m.unsorted = true
+}
+
+// setItemSize records a measurement for items[i], with the details read
+// while measuring, keeping the selection totals in step with it.
+func (m *model) setItemSize(i int, r scanner.ResultItem) {
+	it := &m.items[i]
+	if it.sel {
+		m.selectedSize += r.Reclaimable - it.size
+	}
+	if it.selZip {
+		m.zipSelectedSize += r.Reclaimable - it.size
+	}
+	it.pending = false
+	it.partial = r.Partial
+	it.err = nil
+	if r.Err != nil {
+		it.err = r.Err
+	}
+	it.size, it.apparent = r.Reclaimable, r.Size
+	it.files, it.dirs, it.symlinks = r.Files, r.Dirs, r.Symlinks
+	it.restore = r.Restore
+	it.activity = r.LastActivity
+	it.drift, it.commit, it.protected = driftStatus(r.Drift), lastCommit(r.Git), r.Protected
+}
+
+type remeasureDoneMsg struct{ r scanner.ResultItem }
+
+// startRemeasure measures the item under the cursor again with the scan
+// budgets lifted, e.g. to expand a partial result.
+func (m *model) startRemeasure() tea.Cmd {
+	view := m.viewIndexes()
+	if m.cursor >= len(view) {
+		return nil
+	}
+	it := &m.items[view[m.cursor]]
+	if it.pending {
+		return nil
+	}
+	it.pending = true
+	opts := m.opts
+	opts.MaxEntries, opts.MaxBytes, opts.DirTimeout = 0, 0, 0
+	path, kind := it.path, it.kind
+	return func() tea.Msg {
+		return remeasureDoneMsg{r: scanner.Measure(context.Background(), path, kind, opts)}
+	}
+}
+
+// applyRemeasure swaps a re-measured result into the list and totals.
+func (m *model) applyRemeasure(r scanner.ResultItem) {
+	for j := range m.results {
+		if m.results[j].Path == r.Path {
+			m.totalSize -= m.results[j].Size
+			m.totalReclaimable -= m.results[j].Reclaimable
+			m.results = append(m.results[:j], m.results[j+1:]...)
+			break
+		}
+	}
+	if r.Err == nil {
+		m.results = append(m.results, r)
+		m.totalSize += r.Size
+		m.totalReclaimable += r.Reclaimable
+	}
+	if i := m.itemIndex(r.Path); i >= 0 {
+		m.setItemSize(i, r)
+	}
+	m.resort()
+}
+
+// itemIndex returns the index of the item with the given path, or -1.
+func (m *model) itemIndex(path string) int {
+	if i, ok := m.itemIdx[path]; ok {
+		return i
+	}
+	return -1
+}
+
+// resort re-applies the sort while keeping the cursor on the same item, so
+// live scan updates don't move it out from under the user.
+func (m *model) resort() {
+	m.unsorted = false
+	view := m.viewIndexes()
+	cur := ""
+	if m.cursor < len(view) {
+		cur = m.items[view[m.cursor]].path
+	}
+	m.applySort()
+	if cur == "" {
+		return
+	}
+	for i, idx := range m.viewIndexes() {
+		if m.items[idx].path == cur {
+			m.cursor = i
+			break
+		}
+	}
+	m.adjustScroll()
+}
+
+// browsing reports whether the list accepts navigation and selection keys;
+// that is possible while the scan is still running.
+func (m *model) browsing() bool {
+	return m.st == statusReady || m.st == statusScanning
 }
 
 func (m *model) displayPath(p string) string {
@@ -763,24 +1168,63 @@ func (m *model) headerText() string {
     switch m.st {
     case statusScanning:
         elapsed := time.Since(m.startedAt).Round(time.Millisecond)
-        return fmt.Sprintf("Scanning... %s  Found: %d  Total: %s  Elapsed: %s\nPress ? for help\n\n", m.sp.View(), len(m.results), utils.HumanizeBytes(m.totalSize), elapsed)
+        return fmt.Sprintf("Scanning... %s  Found: %d  Sized: %d  Reclaimable: %s  Apparent: %s  Selected(del): %s  Elapsed: %s%s%s\nPress ? for help; select now, delete/compress once the scan completes\n\n", m.sp.View(), len(m.items), len(m.results), utils.HumanizeBytes(m.totalReclaimable), utils.HumanizeBytes(m.totalSize), utils.HumanizeBytes(m.selectedSize), elapsed, m.errorInfo(), m.filterInfo())
     case statusReady:
-        filterInfo := ""
-        if m.filtering || m.filterText != "" {
-            view := m.viewIndexes()
-            if m.filtering {
-                filterInfo = fmt.Sprintf(" | Filter: /%s_ (%d)", m.filterText, len(view))
-            } else {
-                filterInfo = fmt.Sprintf(" | Filter: /%s (%d)", m.filterText, len(view))
-            }
-        }
-        return fmt.Sprintf("Found: %d  Total: %s  Selected(del): %s  Selected(zip): %s%s  | Keys: ? help, ↑↓ move, ctrl+f/ctrl+b page, Home End, gg/G, space/x [x], z [z], A/X all-[x], Z all-[z], R invert(z→·,x→·,·→x), s sort, r reverse-sort, / filter, d/enter delete|compress, q quit\n\n",
-            len(m.results), utils.HumanizeBytes(m.totalSize), utils.HumanizeBytes(m.selectedSize), utils.HumanizeBytes(m.zipSelectedSize), filterInfo)
+        return fmt.Sprintf("Found: %d  Reclaimable: %s  Apparent: %s  Selected(del): %s  Selected(zip): %s%s%s  | Keys: ? help, ↑↓ move, ctrl+f/ctrl+b page, Home End, gg/G, space/x [x], z [z], A/X all-[x], Z all-[z], O orphans, R invert(z→·,x→·,·→x), s sort, r reverse-sort, / filter, t kind, L drift, e errors, m re-measure, enter/l inspect, D dupes, C caches, H history, T removal:%s, d delete|compress, q quit\n\n",
+            len(m.items), utils.HumanizeBytes(m.totalReclaimable), utils.HumanizeBytes(m.totalSize), utils.HumanizeBytes(m.selectedSize), utils.HumanizeBytes(m.zipSelectedSize), m.errorInfo(), m.filterInfo(), removals[m.removal].name)
     default:
         return ""
     }
 }
 
+// removals are the ways d removes [x] items, in the order T cycles them.
+var removals = []struct {
+	name     string
+	strategy deleter.Strategy
+}{
+	{"delete", deleter.Permanent{}},
+	{"trash", deleter.Trash{}},
+	{"quarantine", deleter.Quarantine{}},
+}
+
+// removalIndex finds a removal by name, falling back to delete.
+func removalIndex(name string) int {
+	for i, r := range removals {
+		if r.name == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// errorInfo summarises scan errors for the header.
+func (m *model) errorInfo() string {
+	if len(m.scanErrs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("  Errors: %d (e)", len(m.scanErrs))
+}
+
+// filterInfo describes the active kind and text filters for the header.
+func (m *model) filterInfo() string {
+	info := ""
+	if m.kindFilter != "" {
+		info += fmt.Sprintf(" | Kind: %s", m.kindFilter)
+	}
+	if m.driftFilter != "" {
+		info += fmt.Sprintf(" | Lockfile: %s", m.driftFilter)
+	}
+	if m.filtering || m.filterText != "" {
+		view := m.viewIndexes()
+		if m.filtering {
+			info += fmt.Sprintf(" | Filter: /%s_ (%d)", m.filterText, len(view))
+		} else {
+			info += fmt.Sprintf(" | Filter: /%s (%d)", m.filterText, len(view))
+		}
+	}
+	return info
+}
+
 func (m *model) helpText() string {
     // Simple help panel with a top separator; avoids side/bottom borders.
     lines := []string{
@@ -794,10 +1238,20 @@ func (m *model) helpText() string {
         "  A / X / ctrl+a Mark all [x] (filtered view)",
         "  Z          Mark all [z] (filtered view)",
         "  R          Invert marks (z→·, x→·, ·→x)",
-        "  s         Toggle sort field (size/path)",
+        "  O          Mark orphans [x] (no package.json next to it; filtered view, git-tracked skipped)",
+        "  s         Toggle sort field (size/path/name/age/files)",
         "  r         Reverse sort",
         "  /         Filter (type, Enter to confirm, Esc to clear)",
-        "  d/enter   Delete selected [x] / Compress selected [z]",
+        "  t         Cycle target kind filter (node_modules, next, ...)",
+        "  L         Cycle lockfile drift filter (stale, extraneous-packages, in-sync)",
+        "  enter/l   Inspect packages of the item (esc/h to go back)",
+        "  e         Show/hide scan errors grouped by kind",
+        "  m         Re-measure the item without scan budgets (expands ≥ partial sizes)",
+        "  D         Duplicate packages across the listed node_modules",
+        "  C         Package manager caches, tool downloads and Node runtimes; p prunes the row under the cursor, U removes unused tool versions, N unused Node versions",
+        "  H         History of deletes, compresses and restores from the operations journal; r restores the archive under the cursor",
+        "  T         Cycle how d removes items: delete, trash (--trash) or quarantine (--quarantine)",
+        "  d         Delete selected [x] / Compress selected [z] (after the scan completes)",
         "  q/esc/ctrl+c/ctrl+d  Quit (cancels delete/compress; cancels scan)",
     }
     w := m.termW
@@ -815,7 +1269,7 @@ func (m *model) helpText() string {
 
 // viewIndexes returns indexes of items matching filter (or all if no filter).
 func (m *model) viewIndexes() []int {
-    if m.filterText == "" {
+    if m.filterText == "" && m.kindFilter == "" && m.driftFilter == "" {
         idx := make([]int, len(m.items))
         for i := range m.items { idx[i] = i }
         return idx
@@ -823,13 +1277,82 @@ func (m *model) viewIndexes() []int {
     q := strings.ToLower(m.filterText)
     out := make([]int, 0, len(m.items))
     for i, it := range m.items {
-        if strings.Contains(strings.ToLower(it.disp), q) || strings.Contains(strings.ToLower(it.path), q) {
+        if m.kindFilter != "" && it.kind != m.kindFilter {
+            continue
+        }
+        if m.driftFilter != "" && it.drift != m.driftFilter {
+            continue
+        }
+        if strings.Contains(strings.ToLower(it.disp), q) || strings.Contains(strings.ToLower(it.path), q) ||
+            strings.Contains(strings.ToLower(it.project.Name), q) {
             out = append(out, i)
         }
     }
     return out
 }
 
+// cycleKindFilter steps the kind filter through the kinds present in the
+// results, then back to showing all.
+func (m *model) cycleKindFilter() {
+	seen := map[string]struct{}{}
+	var kinds []string
+	for _, it := range m.items {
+		if _, ok := seen[it.kind]; !ok {
+			seen[it.kind] = struct{}{}
+			kinds = append(kinds, it.kind)
+		}
+	}
+	sort.Strings(kinds)
+	next := ""
+	if m.kindFilter == "" {
+		if len(kinds) > 0 {
+			next = kinds[0]
+		}
+	} else {
+		for i, k := range kinds {
+			if k == m.kindFilter && i+1 < len(kinds) {
+				next = kinds[i+1]
+			}
+		}
+	}
+	m.kindFilter = next
+	m.cursor = 0
+	m.scrollOffset = 0
+}
+
+// cycleDriftFilter steps the lockfile drift filter through stale, extraneous
+// and in-sync, then back to showing all.
+func (m *model) cycleDriftFilter() {
+	next := scanner.DriftStatuses[0]
+	if m.driftFilter != "" {
+		next = ""
+		for i, s := range scanner.DriftStatuses {
+			if s == m.driftFilter && i+1 < len(scanner.DriftStatuses) {
+				next = scanner.DriftStatuses[i+1]
+			}
+		}
+	}
+	m.driftFilter = next
+	m.cursor = 0
+	m.scrollOffset = 0
+}
+
+// lastCommit is the last commit time of g, zero outside a repository.
+func lastCommit(g *scanner.GitInfo) time.Time {
+	if g == nil {
+		return time.Time{}
+	}
+	return g.LastCommit
+}
+
+// driftStatus is the status of d, or "" when there was nothing to compare.
+func driftStatus(d *scanner.Drift) scanner.DriftStatus {
+	if d == nil {
+		return ""
+	}
+	return d.Status
+}
+
 func (m *model) visibleHeight() int {
     headerLines := strings.Count(m.headerText(), "\n") + 1
     h := m.termH - headerLines - 1
@@ -861,8 +1384,65 @@ var (
 	pathStyleZip      = lipgloss.NewStyle().Foreground(lipgloss.Color("214"))            // orange
 	highlightStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("227")).Bold(true) // yellow
 	headerStyle       = lipgloss.NewStyle().Bold(true)
+	projectStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("111")) // light blue
+	lockStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("245")) // gray
+	ageStyle          = lipgloss.NewStyle().Foreground(lipgloss.Color("180")) // tan
+	kindStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("141")) // lavender
+	pendingStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true) // dark gray
+	errorStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))              // red
+	healthStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("203"))              // salmon
 )
 
+// projectColWidth is the fixed width of the "name@version" column.
+const projectColWidth = 28
+
+// lockLabel renders the lockfile kind for the list column.
+func lockLabel(k scanner.LockfileKind) string {
+	if k == scanner.LockNone {
+		return "-"
+	}
+	return string(k)
+}
+
+// ageLabel renders time since the last project activity, or "-" when unknown.
+func ageLabel(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return utils.HumanizeAge(time.Since(t))
+}
+
+// progressLine reports targets done plus, when the file counts are known, files
+// done and an ETA extrapolated from them. Without counts the ETA falls back to
+// finished targets.
+func progressLine(completed, total int, filesDone, filesTotal int64, started time.Time) string {
+	elapsed := time.Since(started)
+	if filesTotal > 0 {
+		return fmt.Sprintf("%d/%d  Files: %s/%s  %s", completed, total, utils.HumanizeCount(filesDone), utils.HumanizeCount(filesTotal), utils.FormatETA(filesDone, filesTotal, elapsed))
+	}
+	return fmt.Sprintf("%d/%d  %s", completed, total, utils.FormatETA(int64(completed), int64(total), elapsed))
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:n])
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// padRight pads s with spaces to n runes.
+func padRight(s string, n int) string {
+	if l := len([]rune(s)); l < n {
+		return s + strings.Repeat(" ", n-l)
+	}
+	return s
+}
+
 // Choose color for size: dark red > light red > orange > yellow > green > light gray > dark gray
 func sizeColorStyle(b int64) lipgloss.Style {
 	// thresholds in bytes
@@ -890,18 +1470,25 @@ func sizeColorStyle(b int64) lipgloss.Style {
 
 // deletion wiring
 type delProgressMsg struct {
-	completed int
-	total     int
-	path      string
-	err       error
+	completed  int
+	total      int
+	path       string
+	err        error
+	filesDone  int64
+	filesTotal int64
+}
+type delDoneMsg struct {
+	summary    deleter.Summary
+	journalErr error
 }
-type delDoneMsg struct{ summary deleter.Summary }
 
 func (m *model) startDeletion() (tea.Model, tea.Cmd) {
 	m.st = statusDeleting
 	m.sp = spinner.New()
 	m.sp.Spinner = spinner.Dot
 	m.delCompleted = 0
+	m.delStarted = time.Now()
+	m.delFilesDone, m.delFilesTotal = 0, 0
 	targets := m.selectedTargets()
 	m.delTotal = len(targets)
 	ch := make(chan tea.Msg)
@@ -917,7 +1504,7 @@ func (m *model) startDeletion() (tea.Model, tea.Cmd) {
 		go func() {
 			ctx, cancel := context.WithCancel(context.Background())
 			m.delCancel = cancel
-			sum = deleter.DeleteTargets(ctx, targets, m.opts.Concurrency, pch, m.dryRun)
+			sum = deleter.DeleteTargets(ctx, targets, deleter.Options{Concurrency: m.opts.Concurrency, DryRun: m.dryRun, Force: m.force, Strategy: removals[m.removal].strategy}, pch)
 			close(done)
 		}()
 		for {
@@ -926,12 +1513,16 @@ func (m *model) startDeletion() (tea.Model, tea.Cmd) {
 				if !ok {
 					p = deleter.Progress{Completed: m.delTotal, Total: m.delTotal}
 				}
-				ch <- delProgressMsg{completed: p.Completed, total: p.Total, path: p.Path, err: p.Err}
+				ch <- delProgressMsg{completed: p.Completed, total: p.Total, path: p.Path, err: p.Err, filesDone: p.FilesDone, filesTotal: p.FilesTotal}
 				if p.Completed >= p.Total && p.Total > 0 {
 					// wait for summary
 				}
 			case <-done:
-				ch <- delDoneMsg{summary: sum}
+				var jerr error
+				if !m.dryRun {
+					jerr = journal.Journal{}.Record(journal.FromDelete(sum, journal.Op(removals[m.removal].name))...)
+				}
+				ch <- delDoneMsg{summary: sum, journalErr: jerr}
 				close(ch)
 				return
 			}
@@ -962,14 +1553,21 @@ type zipProgressMsg struct {
     dest      string
     written   int64
     err       error
+    filesDone  int64
+    filesTotal int64
+}
+type zipDoneMsg struct {
+    summary    compressor.Summary
+    journalErr error
 }
-type zipDoneMsg struct{ summary compressor.Summary }
 
 func (m *model) startCompression() (tea.Model, tea.Cmd) {
     m.st = statusZipping
     m.sp = spinner.New()
     m.sp.Spinner = spinner.Dot
     m.zipCompleted = 0
+    m.zipStarted = time.Now()
+    m.zipFilesDone, m.zipFilesTotal = 0, 0
     targets := m.selectedZipTargets()
     m.zipTotal = len(targets)
     ch := make(chan tea.Msg)
@@ -982,7 +1580,7 @@ func (m *model) startCompression() (tea.Model, tea.Cmd) {
         go func() {
             ctx, cancel := context.WithCancel(context.Background())
             m.zipCancel = cancel
-            sum = compressor.CompressTargets(ctx, targets, compressor.Options{OutDir: "", Concurrency: m.opts.Concurrency, DeleteAfter: m.zipDeleteAfter}, pch)
+            sum = compressor.CompressTargets(ctx, targets, compressor.Options{OutDir: "", Concurrency: m.opts.Concurrency, DeleteAfter: m.zipDeleteAfter, Force: m.force}, pch)
             close(done)
         }()
         for {
@@ -991,9 +1589,10 @@ func (m *model) startCompression() (tea.Model, tea.Cmd) {
                 if !ok {
                     p = compressor.Progress{Completed: m.zipTotal, Total: m.zipTotal}
                 }
-                ch <- zipProgressMsg{completed: p.Completed, total: p.Total, path: p.Path, dest: p.Dest, written: p.BytesWritten, err: p.Err}
+                ch <- zipProgressMsg{completed: p.Completed, total: p.Total, path: p.Path, dest: p.Dest, written: p.BytesWritten, err: p.Err, filesDone: p.FilesDone, filesTotal: p.FilesTotal}
             case <-done:
-                ch <- zipDoneMsg{summary: sum}
+                jerr := journal.Journal{}.Record(journal.FromCompress(sum)...)
+                ch <- zipDoneMsg{summary: sum, journalErr: jerr}
                 close(ch)
                 return
             }
@@ -1022,11 +1621,51 @@ func (m *model) selectedCount() int {
 	return c
 }
 
+func (m *model) selectedProtectedCount() int {
+	c := 0
+	for _, it := range m.items {
+		if it.sel && it.protected {
+			c++
+		}
+	}
+	return c
+}
+
+// restoreSummary tells how much of the selection can be reinstalled from
+// local caches, i.e. without network, and how many packages that means.
+func (m *model) restoreSummary() string {
+	var packages, cached, noLock int
+	for _, it := range m.items {
+		if !it.sel || it.restore == nil {
+			continue
+		}
+		if !it.restore.Lockfile {
+			noLock++
+			continue
+		}
+		packages += it.restore.Packages
+		cached += it.restore.Cached
+	}
+	s := ""
+	if packages > 0 {
+		pct := cached * 100 / packages
+		line := fmt.Sprintf("Restorable offline: %d%% (%d of %d packages to reinstall are cached).", pct, cached, packages)
+		if pct < 100 {
+			line = errorStyle.Render(line)
+		}
+		s += line + "\n"
+	}
+	if noLock > 0 {
+		s += errorStyle.Render(fmt.Sprintf("%d of them have no lockfile: reinstalling may resolve different versions.", noLock)) + "\n"
+	}
+	return s
+}
+
 func (m *model) selectedTargets() []deleter.Target {
 	var out []deleter.Target
 	for _, it := range m.items {
 		if it.sel {
-			out = append(out, deleter.Target{Path: it.path, Size: it.size})
+			out = append(out, deleter.Target{Path: it.path, Size: it.size, Files: it.files + it.dirs + it.symlinks, Protected: it.protected})
 		}
 	}
 	return out
@@ -1044,7 +1683,7 @@ func (m *model) selectedZipTargets() []compressor.Target {
     var out []compressor.Target
     for _, it := range m.items {
         if it.selZip {
-            out = append(out, compressor.Target{Path: it.path, Size: it.size})
+            out = append(out, compressor.Target{Path: it.path, Size: it.size, Files: it.files, Protected: it.protected})
         }
     }
     return out
@@ -1068,14 +1707,19 @@ func (m *model) removeDeleted(succ []deleter.Target) {
 		kept = append(kept, it)
 	}
 m.items = kept
+	m.reindex()
     // Adjust cursor if necessary
     if m.cursor >= len(m.items) && len(m.items) > 0 {
         m.cursor = len(m.items) - 1
     }
-    // filter results slice as well
+    // filter results slice as well, keeping totals in sync
     newRes := m.results[:0]
     for _, r := range m.results {
         if _, ok := rm[r.Path]; ok {
+            if r.Err == nil {
+                m.totalSize -= r.Size
+                m.totalReclaimable -= r.Reclaimable
+            }
             continue
         }
         newRes = append(newRes, r)
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	cases := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"90d", 90 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"1.5d", 36 * time.Hour},
+		{"36h", 36 * time.Hour},
+	}
+	for _, c := range cases {
+		got, err := ParseAge(c.in)
+		if err != nil {
+			t.Fatalf("ParseAge(%q) error: %v", c.in, err)
+		}
+		if got != c.want {
+			t.Fatalf("ParseAge(%q) = %v; want %v", c.in, got, c.want)
+		}
+	}
+	for _, bad := range []string{"", "d", "-3d", "-36h", "-1s", "soon"} {
+		if _, err := ParseAge(bad); err == nil {
+			t.Fatalf("ParseAge(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// HumanizeCount formats an entry count compactly, e.g. 950 -> "950",
+// 1234 -> "1.2k", 12345 -> "12k", 2500000 -> "2.5M".
+func HumanizeCount(n int64) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 10_000:
+		return fmt.Sprintf("%.0fk", float64(n)/1e3)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// EstimateETA extrapolates the remaining time from the work done so far, in
+// any unit (files, bytes, targets). ok is false until there is something to
+// extrapolate from.
+func EstimateETA(done, total int64, elapsed time.Duration) (eta time.Duration, ok bool) {
+	if done <= 0 || total <= 0 || elapsed <= 0 {
+		return 0, false
+	}
+	if done >= total {
+		return 0, true
+	}
+	rate := float64(done) / elapsed.Seconds()
+	return time.Duration(float64(total-done) / rate * float64(time.Second)), true
+}
+
+// FormatETA renders an estimate for progress views, e.g. "ETA 1m20s".
+func FormatETA(done, total int64, elapsed time.Duration) string {
+	eta, ok := EstimateETA(done, total, elapsed)
+	if !ok {
+		return "ETA --"
+	}
+	return "ETA " + eta.Round(time.Second).String()
+}
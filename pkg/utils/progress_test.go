@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanizeCount(t *testing.T) {
+	cases := []struct {
+		in   int64
+		want string
+	}{
+		{0, "0"},
+		{950, "950"},
+		{1234, "1.2k"},
+		{12345, "12k"},
+		{200000, "200k"},
+		{2500000, "2.5M"},
+	}
+	for _, c := range cases {
+		if got := HumanizeCount(c.in); got != c.want {
+			t.Fatalf("HumanizeCount(%d) = %q; want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestEstimateETA(t *testing.T) {
+	if _, ok := EstimateETA(0, 100, time.Second); ok {
+		t.Fatalf("expected no estimate before any progress")
+	}
+	eta, ok := EstimateETA(25, 100, 10*time.Second)
+	if !ok || eta != 30*time.Second {
+		t.Fatalf("EstimateETA(25, 100, 10s) = %v, %v; want 30s", eta, ok)
+	}
+	if eta, ok := EstimateETA(100, 100, time.Second); !ok || eta != 0 {
+		t.Fatalf("expected zero ETA when done, got %v", eta)
+	}
+}
@@ -1,6 +1,10 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 // HumanizeBytes formats a byte count into a readable string.
 func HumanizeBytes(b int64) string {
@@ -45,3 +49,31 @@ func HumanizeBytesCompact(b int64) string {
 		return fmt.Sprintf("%dB", b)
 	}
 }
+
+// ParseBytes parses a size such as "512", "500M", "4.5G" or "1GB". Units are
+// binary (K = 1024) and case-insensitive; a trailing "B" is optional.
+func ParseBytes(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	num := strings.TrimSuffix(s, "B")
+	mult := int64(1)
+	if n := len(num); n > 0 {
+		switch num[n-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			num = num[:n-1]
+		}
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(f * float64(mult)), nil
+}
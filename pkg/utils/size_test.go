@@ -21,3 +21,31 @@ func TestHumanizeBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestParseBytes(t *testing.T) {
+	cases := []struct {
+		in   string
+		want int64
+	}{
+		{"512", 512},
+		{"10B", 10},
+		{"1k", 1024},
+		{"500M", 500 * 1024 * 1024},
+		{"1.5G", 1536 * 1024 * 1024},
+		{"2GB", 2 * 1024 * 1024 * 1024},
+	}
+	for _, c := range cases {
+		got, err := ParseBytes(c.in)
+		if err != nil {
+			t.Fatalf("ParseBytes(%q) error: %v", c.in, err)
+		}
+		if got != c.want {
+			t.Fatalf("ParseBytes(%q) = %d; want %d", c.in, got, c.want)
+		}
+	}
+	for _, bad := range []string{"", "G", "-1M", "lots"} {
+		if _, err := ParseBytes(bad); err == nil {
+			t.Fatalf("ParseBytes(%q) should fail", bad)
+		}
+	}
+}